@@ -11,6 +11,9 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel v0.0.0-20200521084111-1f6e7753eda5
 	github.com/stretchr/testify v1.5.1
+	github.com/yuin/goldmark v1.1.32
 	golang.org/x/tools v0.0.0-20200710042808-f1c4188a97a1 // indirect
 	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543
 )
+
+replace github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel => ./goIPyKernel
@@ -0,0 +1,325 @@
+package goIPyKernel
+
+import (
+  "fmt"
+  "log"
+  "sync"
+
+  "github.com/gofrs/uuid"
+)
+
+// CommHandler handles the (JSON decoded) data, and any (binary) buffers,
+// of a comm_open, comm_msg or comm_close message received by (or sent to)
+// a Comm.
+//
+type CommHandler func(comm *Comm, data map[string]interface{}, buffers [][]byte)
+
+// Comm is one end of a (custom message) channel between the kernel and the
+// front-end (for example an ipywidgets widget).
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#custom-messages
+//
+type Comm struct {
+  ID         string
+  TargetName string
+
+  manager *CommManager
+  onMsg   CommHandler
+  onClose CommHandler
+}
+
+// OnMsg sets the handler called for each comm_msg the front-end sends to
+// this comm.
+//
+func (comm *Comm) OnMsg(handler CommHandler) {
+  comm.manager.Mutex.Lock()
+  defer comm.manager.Mutex.Unlock()
+  comm.onMsg = handler
+}
+
+// OnClose sets the handler called when the front-end closes this comm.
+//
+func (comm *Comm) OnClose(handler CommHandler) {
+  comm.manager.Mutex.Lock()
+  defer comm.manager.Mutex.Unlock()
+  comm.onClose = handler
+}
+
+// Send sends the data (and any binary buffers) to the front-end end of 
+// this comm (as a comm_msg).
+//
+func (comm *Comm) Send(data map[string]interface{}, buffers ...[]byte) error {
+  return comm.manager.publish("comm_msg", CommMsg{
+    CommID: comm.ID,
+    Data:   ensureCommData(data),
+  }, buffers...)
+}
+
+// Close closes this comm (sending the data to the front-end as a
+// comm_close). The comm's OnClose handler is not called.
+//
+func (comm *Comm) Close(data map[string]interface{}) error {
+  comm.manager.Mutex.Lock()
+  delete(comm.manager.comms, comm.ID)
+  comm.manager.Mutex.Unlock()
+
+  return comm.manager.publish("comm_close", CommMsg{
+    CommID: comm.ID,
+    Data:   ensureCommData(data),
+  })
+}
+
+// CommManager keeps track of the comm targets registered by the adaptor
+// (or the user's code) and the comms currently open.
+//
+type CommManager struct {
+  Mutex sync.Mutex
+
+  targets map[string]CommHandler
+  comms   map[string]*Comm
+
+  // receipt is the receipt of the shell message currently (or most
+  // recently) being handled, which is used as the parent of any messages
+  // published by comms.
+  //
+  receipt MsgReceipt
+}
+
+// NewCommManager creates a CommManager with no targets or comms.
+//
+func NewCommManager() *CommManager {
+  return &CommManager{
+    targets: make(map[string]CommHandler),
+    comms:   make(map[string]*Comm),
+  }
+}
+
+// RegisterTarget registers the handler called, with the new comm (and the
+// comm_open's data and buffers), each time the front-end opens a comm
+// with the targetName.
+//
+func (manager *CommManager) RegisterTarget(targetName string, handler CommHandler) {
+  manager.Mutex.Lock()
+  defer manager.Mutex.Unlock()
+  manager.targets[targetName] = handler
+}
+
+// UnregisterTarget removes the targetName's handler (the comms already
+// opened with the targetName remain open).
+//
+func (manager *CommManager) UnregisterTarget(targetName string) {
+  manager.Mutex.Lock()
+  defer manager.Mutex.Unlock()
+  delete(manager.targets, targetName)
+}
+
+// Open opens a (kernel initiated) comm with the front-end's targetName
+// (sending the data to the front-end as a comm_open).
+//
+func (manager *CommManager) Open(
+  targetName string,
+  data       map[string]interface{},
+) (*Comm, error) {
+  commID, err := uuid.NewV4()
+  if err != nil {
+    return nil, err
+  }
+  comm := manager.addComm(commID.String(), targetName)
+
+  err = manager.publish("comm_open", CommOpen{
+    CommID:     comm.ID,
+    TargetName: targetName,
+    Data:       ensureCommData(data),
+  })
+  if err != nil {
+    manager.Mutex.Lock()
+    delete(manager.comms, comm.ID)
+    manager.Mutex.Unlock()
+    return nil, err
+  }
+  return comm, nil
+}
+
+// Comm returns the open comm with the commID (or nil if there is none).
+//
+func (manager *CommManager) Comm(commID string) *Comm {
+  manager.Mutex.Lock()
+  defer manager.Mutex.Unlock()
+  return manager.comms[commID]
+}
+
+// Reset forgets all targets and (without closing them) comms, for example
+// when the adaptor is restarted.
+//
+func (manager *CommManager) Reset() {
+  manager.Mutex.Lock()
+  defer manager.Mutex.Unlock()
+  manager.targets = make(map[string]CommHandler)
+  manager.comms   = make(map[string]*Comm)
+}
+
+// SetReceipt records the receipt of the shell message being handled, so
+// that any messages published by comms have it as their parent.
+//
+func (manager *CommManager) SetReceipt(receipt MsgReceipt) {
+  manager.Mutex.Lock()
+  defer manager.Mutex.Unlock()
+  manager.receipt = receipt
+}
+
+// HandleCommOpen handles a comm_open from the front-end by calling the
+// target's handler with the new comm. Comms opened with an unknown
+// target are immediately closed again.
+//
+func (manager *CommManager) HandleCommOpen(receipt MsgReceipt) error {
+  var request CommOpen
+  if err := receipt.Msg.ContentAs(&request); err != nil {
+    return err
+  }
+
+  manager.Mutex.Lock()
+  handler, ok := manager.targets[request.TargetName]
+  manager.Mutex.Unlock()
+
+  if !ok {
+    log.Println("No comm target registered for: ", request.TargetName)
+    return receipt.Publish("comm_close", CommMsg{
+      CommID: request.CommID,
+      Data:   map[string]interface{}{},
+    })
+  }
+
+  handler(
+    manager.addComm(request.CommID, request.TargetName),
+    request.Data,
+    receipt.Msg.Buffers,
+  )
+  return nil
+}
+
+// HandleCommMsg passes a comm_msg (with its buffers) from the front-end
+// to its comm's OnMsg handler.
+//
+func (manager *CommManager) HandleCommMsg(receipt MsgReceipt) error {
+  comm, data, err := manager.commFor(receipt)
+  if err != nil {
+    return err
+  }
+
+  manager.Mutex.Lock()
+  handler := comm.onMsg
+  manager.Mutex.Unlock()
+
+  if handler != nil {
+    handler(comm, data, receipt.Msg.Buffers)
+  }
+  return nil
+}
+
+// HandleCommClose forgets the comm closed by the front-end, after calling
+// its OnClose handler.
+//
+func (manager *CommManager) HandleCommClose(receipt MsgReceipt) error {
+  comm, data, err := manager.commFor(receipt)
+  if err != nil {
+    return err
+  }
+
+  manager.Mutex.Lock()
+  handler := comm.onClose
+  delete(manager.comms, comm.ID)
+  manager.Mutex.Unlock()
+
+  if handler != nil {
+    handler(comm, data, receipt.Msg.Buffers)
+  }
+  return nil
+}
+
+// HandleCommInfoRequest replies with the open comms (restricted to those
+// of the requested target_name, if any).
+//
+func (manager *CommManager) HandleCommInfoRequest(receipt MsgReceipt) error {
+  var request CommInfoRequest
+  if err := receipt.Msg.ContentAs(&request); err != nil {
+    return receipt.Reply("comm_info_reply", CommInfoReply{
+      Status:       "error",
+      Comms:        map[string]interface{}{},
+      ErrorContent: invalidContentError(err),
+    })
+  }
+
+  comms := make(map[string]interface{})
+  manager.Mutex.Lock()
+  for commID, comm := range manager.comms {
+    if request.TargetName == "" || request.TargetName == comm.TargetName {
+      comms[commID] = map[string]interface{}{ "target_name": comm.TargetName }
+    }
+  }
+  manager.Mutex.Unlock()
+
+  return receipt.Reply("comm_info_reply", CommInfoReply{
+    Status: "ok",
+    Comms:  comms,
+  })
+}
+
+// addComm records a new (open) comm.
+//
+func (manager *CommManager) addComm(commID, targetName string) *Comm {
+  comm := &Comm{
+    ID:         commID,
+    TargetName: targetName,
+    manager:    manager,
+  }
+  manager.Mutex.Lock()
+  defer manager.Mutex.Unlock()
+  manager.comms[commID] = comm
+  return comm
+}
+
+// commFor returns the (open) comm to which the comm_msg or comm_close
+// message was sent together with the message's data.
+//
+func (manager *CommManager) commFor(
+  receipt MsgReceipt,
+) (*Comm, map[string]interface{}, error) {
+  var request CommMsg
+  if err := receipt.Msg.ContentAs(&request); err != nil {
+    return nil, nil, err
+  }
+
+  comm := manager.Comm(request.CommID)
+  if comm == nil {
+    return nil, nil, fmt.Errorf("no open comm with comm_id: %q", request.CommID)
+  }
+  return comm, request.Data, nil
+}
+
+// publish publishes a comm message on the IOPub socket (with the current
+// receipt's message as its parent).
+//
+func (manager *CommManager) publish(
+  msgType string,
+  content interface{},
+  buffers ...[]byte,
+) error {
+  manager.Mutex.Lock()
+  receipt := manager.receipt
+  manager.Mutex.Unlock()
+
+  if receipt.Sockets.IOPubSocket.Socket == nil {
+    return fmt.Errorf("can not send a %s before the kernel is running", msgType)
+  }
+  return receipt.Publish(msgType, content, buffers...)
+}
+
+// ensureCommData returns the data, or an empty map if the data is nil
+// (the data of a comm message must always be a JSON object).
+//
+func ensureCommData(data map[string]interface{}) map[string]interface{} {
+  if data == nil {
+    return map[string]interface{}{}
+  }
+  return data
+}
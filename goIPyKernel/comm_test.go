@@ -0,0 +1,186 @@
+package goIPyKernel
+
+import(
+  "context"
+  "fmt"
+  "testing"
+  "time"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+// subscribeIOPub subscribes to the kernel's IOPub socket, (re)sending a
+// kernel_info_request on the shell socket until the subscription is
+// receiving the kernel's status messages.
+//
+func subscribeIOPub(
+  t        *testing.T,
+  connInfo ConnectionInfo,
+  shell    zmq4.Socket,
+) zmq4.Socket {
+  signer := testSigner(t, connInfo.Key)
+  iopub := zmq4.NewSub(context.Background())
+  err := iopub.Dial(fmt.Sprintf("tcp://127.0.0.1:%d", connInfo.IOPubPort))
+  assert.NoError(t, err, "could not dial the iopub socket")
+  err = iopub.SetOption(zmq4.OptionSubscribe, "")
+  assert.NoError(t, err, "could not subscribe to the iopub socket")
+
+  received := make(chan struct{})
+  go func() {
+    defer close(received)
+    iopub.Recv()
+  }()
+  for {
+    request(t, shell, signer, "kernel_info_request", nil)
+    select {
+    case <-received:
+      return iopub
+    case <-time.After(100 * time.Millisecond):
+    }
+  }
+}
+
+// nextIOPub returns the next message of msgType published on IOPub.
+//
+func nextIOPub(
+  t       *testing.T,
+  iopub   zmq4.Socket,
+  signer  *MsgSigner,
+  msgType string,
+) ComposedMsg {
+  for {
+    wireMsg, err := iopub.Recv()
+    assert.NoError(t, err, "could not receive from iopub")
+    msg, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+    assert.NoError(t, err, "could not decode the iopub message")
+    if msg.Header.MsgType == msgType {
+      return msg
+    }
+  }
+}
+
+func TestComms(t *testing.T) {
+  kernel, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  iopub := subscribeIOPub(t, frontEnd.connInfo, shell)
+  defer iopub.Close()
+
+  // the adaptor registered the "echo" target...
+  assert.Same(t, kernel.Comms, kernel.Adaptor.(*testAdaptor).comms,
+    "comms were not registered",
+  )
+
+  // ... so comms opened with it echo their messages
+  send(t, shell, signer, "comm_open", map[string]interface{}{
+    "comm_id":     "echo-comm",
+    "target_name": "echo",
+    "data":        map[string]interface{}{},
+  })
+  send(t, shell, signer, "comm_msg", map[string]interface{}{
+    "comm_id": "echo-comm",
+    "data":    map[string]interface{}{ "value": "hello" },
+  }, []byte("\x00binary\xff"))
+  msg := nextIOPub(t, iopub, signer, "comm_msg")
+  assert.Equal(t, map[string]interface{}{
+    "comm_id": "echo-comm",
+    "data":    map[string]interface{}{ "value": "hello" },
+  }, msg.Content)
+  assert.Equal(t, [][]byte{ []byte("\x00binary\xff") }, msg.Buffers,
+    "the comm's buffers were not passed to its handler")
+
+  reply := request(t, shell, signer, "comm_info_request",
+    map[string]interface{}{ "target_name": "echo" },
+  )
+  assert.Equal(t, "comm_info_reply", reply.Header.MsgType)
+  assert.Equal(t, map[string]interface{}{
+    "echo-comm": map[string]interface{}{ "target_name": "echo" },
+  }, reply.Content.(map[string]interface{})["comms"])
+
+  // comms opened with an unknown target are closed again
+  send(t, shell, signer, "comm_open", map[string]interface{}{
+    "comm_id":     "unknown-comm",
+    "target_name": "unknown",
+    "data":        map[string]interface{}{},
+  })
+  msg = nextIOPub(t, iopub, signer, "comm_close")
+  assert.Equal(t, "unknown-comm", msg.Content.(map[string]interface{})["comm_id"])
+
+  // comms may be opened by the kernel
+  comm, err := kernel.Comms.Open("front-end", map[string]interface{}{ "x": 1.0 })
+  assert.NoError(t, err, "could not open a comm")
+  msg = nextIOPub(t, iopub, signer, "comm_open")
+  assert.Equal(t, map[string]interface{}{
+    "comm_id":     comm.ID,
+    "target_name": "front-end",
+    "data":        map[string]interface{}{ "x": 1.0 },
+  }, msg.Content)
+
+  // closed comms are forgotten
+  send(t, shell, signer, "comm_close", map[string]interface{}{
+    "comm_id": "echo-comm",
+    "data":    map[string]interface{}{},
+  })
+  assert.NoError(t, comm.Close(nil))
+  reply = request(t, shell, signer, "comm_info_request", nil)
+  assert.Empty(t, reply.Content.(map[string]interface{})["comms"])
+}
+
+func TestCommHandlersReceiveBuffers(t *testing.T) {
+  comms := NewCommManager()
+
+  var opened, received, closed [][]byte
+  comms.RegisterTarget("buffers",
+    func(comm *Comm, data map[string]interface{}, buffers [][]byte) {
+      opened = buffers
+      comm.OnMsg(
+        func(comm *Comm, data map[string]interface{}, buffers [][]byte) {
+          received = buffers
+        },
+      )
+      comm.OnClose(
+        func(comm *Comm, data map[string]interface{}, buffers [][]byte) {
+          closed = buffers
+        },
+      )
+    },
+  )
+
+  receiptFor := func(
+    msgType string,
+    content map[string]interface{},
+    buffers ...[]byte,
+  ) MsgReceipt {
+    msg, err := NewMsg(msgType, ComposedMsg{})
+    assert.NoError(t, err, "could not create the message")
+    msg.Content = content
+    msg.Buffers = buffers
+    return MsgReceipt{ Msg: msg }
+  }
+
+  err := comms.HandleCommOpen(receiptFor("comm_open", map[string]interface{}{
+    "comm_id":     "buffers-comm",
+    "target_name": "buffers",
+    "data":        map[string]interface{}{},
+  }, []byte("open")))
+  assert.NoError(t, err)
+  assert.Equal(t, [][]byte{ []byte("open") }, opened)
+
+  err = comms.HandleCommMsg(receiptFor("comm_msg", map[string]interface{}{
+    "comm_id": "buffers-comm",
+    "data":    map[string]interface{}{},
+  }, []byte("msg"), []byte{}))
+  assert.NoError(t, err)
+  assert.Equal(t, [][]byte{ []byte("msg"), {} }, received)
+
+  err = comms.HandleCommClose(receiptFor("comm_close", map[string]interface{}{
+    "comm_id": "buffers-comm",
+    "data":    map[string]interface{}{},
+  }, []byte("close")))
+  assert.NoError(t, err)
+  assert.Equal(t, [][]byte{ []byte("close") }, closed)
+}
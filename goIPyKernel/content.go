@@ -0,0 +1,519 @@
+package goIPyKernel
+
+import (
+  "encoding/json"
+  "fmt"
+  "reflect"
+  "unicode/utf8"
+)
+
+// The typed contents of the Jupyter (5.x) shell, control, IOPub and stdin
+// messages. Received messages are decoded (by WireMsgToTypedMsg) into a
+// pointer to their msg_type's struct, with the protocol's defaults for any
+// missing (optional) fields.
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html
+//
+
+// ErrorContent holds the fields common to every reply whose status is
+// "error". (Replies embed a *ErrorContent which is nil, and so not
+// encoded, unless the status is "error").
+//
+type ErrorContent struct {
+  EName     string   `json:"ename"`
+  EValue    string   `json:"evalue"`
+  Traceback []string `json:"traceback"`
+}
+
+// NewErrorContent describes the execErr as ErrorContent (with a coloured
+// traceback).
+//
+func NewErrorContent(execErr *ExecutionError) *ErrorContent {
+  return &ErrorContent{
+    EName:     execErr.Name,
+    EValue:    execErr.Value,
+    Traceback: execErr.ColouredTraceback(),
+  }
+}
+
+// invalidContentError describes a request whose content could not be
+// decoded (err) as the ErrorContent of its reply.
+//
+func invalidContentError(err error) *ErrorContent {
+  return &ErrorContent{
+    EName:     "InvalidContentError",
+    EValue:    err.Error(),
+    Traceback: []string{},
+  }
+}
+
+// Shell messages
+
+// ExecuteRequest is the content of an execute_request.
+//
+type ExecuteRequest struct {
+  Code            string                 `json:"code"`
+  Silent          bool                   `json:"silent"`
+  StoreHistory    bool                   `json:"store_history"`
+  UserExpressions map[string]interface{} `json:"user_expressions"`
+  AllowStdin      bool                   `json:"allow_stdin"`
+  StopOnError     bool                   `json:"stop_on_error"`
+}
+
+func (req *ExecuteRequest) setDefaults() {
+  req.StoreHistory = true
+  req.AllowStdin   = true
+  req.StopOnError  = true
+}
+
+// ExecuteReply is the content of an execute_reply.
+//
+type ExecuteReply struct {
+  Status          string                    `json:"status"`
+  ExecutionCount  int                       `json:"execution_count"`
+  UserExpressions map[string]UserExpression `json:"user_expressions"`
+  Payload         []interface{}             `json:"payload,omitempty"`
+  *ErrorContent
+}
+
+// SetNextInputPayload is the content of an execute_reply's set_next_input
+// payload (see MsgReceipt.SetNextInput).
+//
+type SetNextInputPayload struct {
+  Source  string `json:"source"`
+  Text    string `json:"text"`
+  Replace bool   `json:"replace"`
+}
+
+// PagePayload is the content of an execute_reply's page payload (see
+// MsgReceipt.Page).
+//
+type PagePayload struct {
+  Source string  `json:"source"`
+  Data   MIMEMap `json:"data"`
+  Start  int     `json:"start"`
+}
+
+// AskExitPayload is the content of an execute_reply's ask_exit payload
+// (see MsgReceipt.AskExit).
+//
+type AskExitPayload struct {
+  Source     string `json:"source"`
+  KeepKernel bool   `json:"keepkernel"`
+}
+
+// UserExpression is the (ok or error) result, in an execute_reply, of one
+// of the execute_request's user_expressions.
+//
+type UserExpression struct {
+  Status   string  `json:"status"`
+  Data     MIMEMap `json:"data,omitempty"`
+  Metadata MIMEMap `json:"metadata,omitempty"`
+  *ErrorContent
+}
+
+// NewUserExpression describes the result of evaluating a user expression
+// (either its value or the error which evaluating it raised).
+//
+func NewUserExpression(result ExpressionResult) UserExpression {
+  if result.Err != nil {
+    return UserExpression{
+      Status:       "error",
+      ErrorContent: NewErrorContent(AsExecutionError(result.Err)),
+    }
+  }
+  return UserExpression{
+    Status:   "ok",
+    Data:     EnsureMIMEMap(result.Data.Data),
+    Metadata: EnsureMIMEMap(result.Data.Metadata),
+  }
+}
+
+// InspectRequest is the content of an inspect_request.
+//
+type InspectRequest struct {
+  Code        string `json:"code"`
+  CursorPos   int    `json:"cursor_pos"`
+  DetailLevel int    `json:"detail_level"`
+}
+
+func (req *InspectRequest) setDefaults() {
+  req.CursorPos = -1
+}
+
+func (req *InspectRequest) fixDefaults() {
+  req.CursorPos = defaultCursorPos(req.Code, req.CursorPos)
+}
+
+// InspectReply is the content of an inspect_reply.
+//
+type InspectReply struct {
+  Status   string  `json:"status"`
+  Found    bool    `json:"found"`
+  Data     MIMEMap `json:"data"`
+  Metadata MIMEMap `json:"metadata"`
+  *ErrorContent
+}
+
+// CompleteRequest is the content of a complete_request.
+//
+type CompleteRequest struct {
+  Code      string `json:"code"`
+  CursorPos int    `json:"cursor_pos"`
+}
+
+func (req *CompleteRequest) setDefaults() {
+  req.CursorPos = -1
+}
+
+func (req *CompleteRequest) fixDefaults() {
+  req.CursorPos = defaultCursorPos(req.Code, req.CursorPos)
+}
+
+// CompleteReply is the content of a complete_reply.
+//
+type CompleteReply struct {
+  Status      string                 `json:"status"`
+  Matches     []string               `json:"matches"`
+  CursorStart int                    `json:"cursor_start"`
+  CursorEnd   int                    `json:"cursor_end"`
+  Metadata    map[string]interface{} `json:"metadata"`
+  *ErrorContent
+}
+
+// HistoryRequest is the content of a history_request.
+//
+type HistoryRequest struct {
+  Output         bool   `json:"output"`
+  Raw            bool   `json:"raw"`
+  HistAccessType string `json:"hist_access_type"`
+  Session        int    `json:"session"`
+  Start          int    `json:"start"`
+  Stop           int    `json:"stop"`
+  N              int    `json:"n"`
+  Pattern        string `json:"pattern"`
+  Unique         bool   `json:"unique"`
+}
+
+// HistoryReply is the content of a history_reply.
+//
+type HistoryReply struct {
+  Status  string        `json:"status"`
+  History []interface{} `json:"history"`
+  *ErrorContent
+}
+
+// IsCompleteRequest is the content of an is_complete_request.
+//
+type IsCompleteRequest struct {
+  Code string `json:"code"`
+}
+
+// IsCompleteReply is the content of an is_complete_reply.
+//
+type IsCompleteReply struct {
+  Status string `json:"status"`
+  Indent string `json:"indent,omitempty"`
+}
+
+// ConnectRequest is the content of a (deprecated) connect_request.
+//
+type ConnectRequest struct{}
+
+// ConnectReply is the content of a (deprecated) connect_reply.
+//
+type ConnectReply struct {
+  ShellPort   int `json:"shell_port"`
+  IOPubPort   int `json:"iopub_port"`
+  StdinPort   int `json:"stdin_port"`
+  HBPort      int `json:"hb_port"`
+  ControlPort int `json:"control_port"`
+}
+
+// CommInfoRequest is the content of a comm_info_request.
+//
+type CommInfoRequest struct {
+  TargetName string `json:"target_name"`
+}
+
+// CommInfoReply is the content of a comm_info_reply.
+//
+type CommInfoReply struct {
+  Status string                 `json:"status"`
+  Comms  map[string]interface{} `json:"comms"`
+  *ErrorContent
+}
+
+// KernelInfoRequest is the content of a kernel_info_request (whose reply
+// is the adaptor's KernelInfo).
+//
+type KernelInfoRequest struct{}
+
+// Control messages
+
+// ShutdownRequest is the content of a shutdown_request (whose reply is a
+// ShutdownReply).
+//
+type ShutdownRequest struct {
+  Restart bool `json:"restart"`
+}
+
+// InterruptRequest is the content of an interrupt_request.
+//
+type InterruptRequest struct{}
+
+// InterruptReply is the content of an interrupt_reply.
+//
+type InterruptReply struct {
+  Status string `json:"status"`
+  *ErrorContent
+}
+
+// IOPub messages
+
+// Stream is the content of a stream message.
+//
+type Stream struct {
+  Name string `json:"name"`
+  Text string `json:"text"`
+}
+
+// DisplayData is the content of a display_data (or update_display_data)
+// message.
+//
+type DisplayData struct {
+  Data      MIMEMap `json:"data"`
+  Metadata  MIMEMap `json:"metadata"`
+  Transient MIMEMap `json:"transient"`
+}
+
+// ExecuteInput is the content of an execute_input message.
+//
+type ExecuteInput struct {
+  Code           string `json:"code"`
+  ExecutionCount int    `json:"execution_count"`
+}
+
+// ExecuteResult is the content of an execute_result message.
+//
+type ExecuteResult struct {
+  ExecutionCount int     `json:"execution_count"`
+  Data           MIMEMap `json:"data"`
+  Metadata       MIMEMap `json:"metadata"`
+}
+
+// Error is the content of an (IOPub) error message.
+//
+type Error struct {
+  EName     string   `json:"ename"`
+  EValue    string   `json:"evalue"`
+  Traceback []string `json:"traceback"`
+}
+
+// Status is the content of a status message.
+//
+type Status struct {
+  ExecutionState string `json:"execution_state"`
+}
+
+// ClearOutput is the content of a clear_output message.
+//
+type ClearOutput struct {
+  Wait bool `json:"wait"`
+}
+
+// CommOpen is the content of a comm_open message.
+//
+type CommOpen struct {
+  CommID     string                 `json:"comm_id"`
+  TargetName string                 `json:"target_name"`
+  Data       map[string]interface{} `json:"data"`
+}
+
+// CommMsg is the content of a comm_msg (or comm_close) message.
+//
+type CommMsg struct {
+  CommID string                 `json:"comm_id"`
+  Data   map[string]interface{} `json:"data"`
+}
+
+// IOPubWelcome is the content of an iopub_welcome message.
+//
+type IOPubWelcome struct {
+  Subscription string `json:"subscription"`
+}
+
+// Stdin messages
+
+// InputRequest is the content of an input_request.
+//
+type InputRequest struct {
+  Prompt   string `json:"prompt"`
+  Password bool   `json:"password"`
+}
+
+// InputReply is the content of an input_reply.
+//
+type InputReply struct {
+  Value string `json:"value"`
+}
+
+// contentTypes maps each msg_type to its content's struct.
+//
+var contentTypes = map[string]reflect.Type{
+  "execute_request":     reflect.TypeOf(ExecuteRequest{}),
+  "execute_reply":       reflect.TypeOf(ExecuteReply{}),
+  "inspect_request":     reflect.TypeOf(InspectRequest{}),
+  "inspect_reply":       reflect.TypeOf(InspectReply{}),
+  "complete_request":    reflect.TypeOf(CompleteRequest{}),
+  "complete_reply":      reflect.TypeOf(CompleteReply{}),
+  "history_request":     reflect.TypeOf(HistoryRequest{}),
+  "history_reply":       reflect.TypeOf(HistoryReply{}),
+  "is_complete_request": reflect.TypeOf(IsCompleteRequest{}),
+  "is_complete_reply":   reflect.TypeOf(IsCompleteReply{}),
+  "connect_request":     reflect.TypeOf(ConnectRequest{}),
+  "connect_reply":       reflect.TypeOf(ConnectReply{}),
+  "comm_info_request":   reflect.TypeOf(CommInfoRequest{}),
+  "comm_info_reply":     reflect.TypeOf(CommInfoReply{}),
+  "kernel_info_request": reflect.TypeOf(KernelInfoRequest{}),
+  "kernel_info_reply":   reflect.TypeOf(KernelInfo{}),
+  "shutdown_request":    reflect.TypeOf(ShutdownRequest{}),
+  "shutdown_reply":      reflect.TypeOf(ShutdownReply{}),
+  "interrupt_request":   reflect.TypeOf(InterruptRequest{}),
+  "interrupt_reply":     reflect.TypeOf(InterruptReply{}),
+  "stream":              reflect.TypeOf(Stream{}),
+  "display_data":        reflect.TypeOf(DisplayData{}),
+  "update_display_data": reflect.TypeOf(DisplayData{}),
+  "execute_input":       reflect.TypeOf(ExecuteInput{}),
+  "execute_result":      reflect.TypeOf(ExecuteResult{}),
+  "error":               reflect.TypeOf(Error{}),
+  "status":              reflect.TypeOf(Status{}),
+  "clear_output":        reflect.TypeOf(ClearOutput{}),
+  "comm_open":           reflect.TypeOf(CommOpen{}),
+  "comm_msg":            reflect.TypeOf(CommMsg{}),
+  "comm_close":          reflect.TypeOf(CommMsg{}),
+  "iopub_welcome":       reflect.TypeOf(IOPubWelcome{}),
+  "input_request":       reflect.TypeOf(InputRequest{}),
+  "input_reply":         reflect.TypeOf(InputReply{}),
+}
+
+// contentDefaulter is implemented by contents with (non-zero) protocol
+// defaults, which setDefaults sets before the JSON is decoded.
+//
+type contentDefaulter interface {
+  setDefaults()
+}
+
+// contentFixer is implemented by contents with protocol defaults which
+// depend upon other fields, which fixDefaults sets after the JSON is
+// decoded.
+//
+type contentFixer interface {
+  fixDefaults()
+}
+
+// NewContent returns a pointer to the (defaulted) content struct of the
+// msgType, or nil if the msgType is unknown.
+//
+func NewContent(msgType string) interface{} {
+  contentType, ok := contentTypes[msgType]
+  if !ok {
+    return nil
+  }
+  content := reflect.New(contentType).Interface()
+  if defaulter, ok := content.(contentDefaulter); ok {
+    defaulter.setDefaults()
+  }
+  return content
+}
+
+// DecodeContent decodes the JSON content of a message of msgType into a
+// pointer to its (defaulted) content struct. The contents of unknown
+// msgTypes are decoded into a map[string]interface{}.
+//
+func DecodeContent(msgType string, content []byte) (interface{}, error) {
+  typed := NewContent(msgType)
+  if typed == nil {
+    var untyped map[string]interface{}
+    err := json.Unmarshal(content, &untyped)
+    return untyped, err
+  }
+  if err := json.Unmarshal(content, typed); err != nil {
+    return nil, err
+  }
+  if fixer, ok := typed.(contentFixer); ok {
+    fixer.fixDefaults()
+  }
+  return typed, nil
+}
+
+// InvalidContentTargetError is returned by ContentAs when its target is
+// not a (non-nil) pointer into which the content can be copied.
+//
+type InvalidContentTargetError struct {
+  Type reflect.Type
+}
+
+func (e *InvalidContentTargetError) Error() string {
+  if e.Type == nil {
+    return "can not copy a message's content into a nil target"
+  }
+  if e.Type.Kind() != reflect.Ptr {
+    return fmt.Sprintf(
+      "can not copy a message's content into a non-pointer %s", e.Type,
+    )
+  }
+  return fmt.Sprintf("can not copy a message's content into a nil %s", e.Type)
+}
+
+// ContentAs copies the message's content into the target (a pointer to a
+// content struct). Contents which are not already of the target's type
+// (for example a map[string]interface{} built by hand) are converted via
+// their JSON encoding, with the protocol's defaults for any missing
+// fields. An InvalidContentTargetError is returned if the target is nil
+// or not a pointer.
+//
+func (msg ComposedMsg) ContentAs(target interface{}) error {
+  targetValue := reflect.ValueOf(target)
+  if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() {
+    return &InvalidContentTargetError{ Type: reflect.TypeOf(target) }
+  }
+
+  content := reflect.ValueOf(msg.Content)
+  if content.IsValid() && content.Type() == targetValue.Type() {
+    if !content.IsNil() {
+      targetValue.Elem().Set(content.Elem())
+      return nil
+    }
+    // a nil (typed) content is treated as a missing content
+    msg.Content = nil
+  }
+
+  if defaulter, ok := target.(contentDefaulter); ok {
+    defaulter.setDefaults()
+  }
+  if msg.Content != nil {
+    encoded, err := json.Marshal(msg.Content)
+    if err != nil {
+      return err
+    }
+    if err := json.Unmarshal(encoded, target); err != nil {
+      return &InvalidJSONError{ Frame: "content", Err: err }
+    }
+  }
+  if fixer, ok := target.(contentFixer); ok {
+    fixer.fixDefaults()
+  }
+  return nil
+}
+
+// defaultCursorPos returns the cursorPos, or if it is missing (negative)
+// the end of the code (in unicode code points, as the protocol
+// specifies).
+//
+func defaultCursorPos(code string, cursorPos int) int {
+  if cursorPos < 0 {
+    return utf8.RuneCountInString(code)
+  }
+  return cursorPos
+}
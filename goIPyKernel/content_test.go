@@ -0,0 +1,137 @@
+package goIPyKernel
+
+import(
+  "testing"
+
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestDecodeContentDefaults(t *testing.T) {
+  content, err := DecodeContent("execute_request", []byte(`{"code": "1+1"}`))
+  assert.NoError(t, err, "could not decode an execute_request")
+  assert.Equal(t, &ExecuteRequest{
+    Code:         "1+1",
+    StoreHistory: true,
+    AllowStdin:   true,
+    StopOnError:  true,
+  }, content)
+
+  content, err = DecodeContent("execute_request",
+    []byte(`{"code": "", "silent": true, "store_history": false, "stop_on_error": false}`),
+  )
+  assert.NoError(t, err, "could not decode an execute_request")
+  assert.Equal(t, &ExecuteRequest{ Silent: true, AllowStdin: true }, content)
+
+  // a missing cursor_pos is the end of the code (in code points)
+  content, err = DecodeContent("complete_request", []byte(`{"code": "fmt.Pr€"}`))
+  assert.NoError(t, err, "could not decode a complete_request")
+  assert.Equal(t, &CompleteRequest{ Code: "fmt.Pr€", CursorPos: 7 }, content)
+
+  content, err = DecodeContent("inspect_request", []byte(`{"code": "fmt", "cursor_pos": 1}`))
+  assert.NoError(t, err, "could not decode an inspect_request")
+  assert.Equal(t, &InspectRequest{ Code: "fmt", CursorPos: 1 }, content)
+
+  content, err = DecodeContent("shutdown_request", []byte(`{}`))
+  assert.NoError(t, err, "could not decode a shutdown_request")
+  assert.Equal(t, &ShutdownRequest{}, content)
+
+  // unknown messages are left as JSON objects
+  content, err = DecodeContent("custom_request", []byte(`{"x": 1}`))
+  assert.NoError(t, err, "could not decode a custom_request")
+  assert.Equal(t, map[string]interface{}{ "x": 1.0 }, content)
+
+  _, err = DecodeContent("execute_request", []byte(`{"silent": "yes"}`))
+  assert.Error(t, err, "a string silent should not decode")
+}
+
+func TestWireMsgToTypedMsg(t *testing.T) {
+  signer := testSigner(t, "a test key")
+
+  msg, err := NewMsg("shutdown_request", ComposedMsg{})
+  assert.NoError(t, err, "could not create the message")
+  msg.Content = map[string]interface{}{}
+  msgParts, err := msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the message")
+  frames := append([][]byte{ []byte("<IDS|MSG>") }, msgParts...)
+
+  typed, _, err := WireMsgToTypedMsg(frames, signer)
+  assert.NoError(t, err, "could not decode the message")
+  assert.Equal(t, &ShutdownRequest{}, typed.Content)
+
+  untyped, _, err := WireMsgToComposedMsg(frames, signer)
+  assert.NoError(t, err, "could not decode the message")
+  assert.Equal(t, map[string]interface{}{}, untyped.Content)
+
+  msg.Content = map[string]interface{}{ "restart": "yes" }
+  msgParts, err = msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the message")
+  frames = append([][]byte{ []byte("<IDS|MSG>") }, msgParts...)
+  _, _, err = WireMsgToTypedMsg(frames, signer)
+  assert.IsType(t, &InvalidJSONError{}, err)
+}
+
+func TestContentAs(t *testing.T) {
+  var request ExecuteRequest
+
+  // typed contents are copied...
+  msg := ComposedMsg{ Content: &ExecuteRequest{ Code: "typed" } }
+  assert.NoError(t, msg.ContentAs(&request))
+  assert.Equal(t, ExecuteRequest{ Code: "typed" }, request)
+
+  // ... while other contents are converted (with defaults)
+  request = ExecuteRequest{}
+  msg = ComposedMsg{ Content: map[string]interface{}{ "code": "untyped" } }
+  assert.NoError(t, msg.ContentAs(&request))
+  assert.Equal(t, ExecuteRequest{
+    Code:         "untyped",
+    StoreHistory: true,
+    AllowStdin:   true,
+    StopOnError:  true,
+  }, request)
+
+  var reply InputReply
+  msg = ComposedMsg{ Content: map[string]interface{}{ "value": 42 } }
+  assert.IsType(t, &InvalidJSONError{}, msg.ContentAs(&reply))
+
+  // a nil (typed) content is a missing content
+  request = ExecuteRequest{}
+  msg = ComposedMsg{ Content: (*ExecuteRequest)(nil) }
+  assert.NoError(t, msg.ContentAs(&request))
+  assert.True(t, request.StopOnError, "the defaults were not set")
+
+  // targets must be non-nil pointers
+  msg = ComposedMsg{ Content: &ExecuteRequest{ Code: "typed" } }
+  for _, target := range []interface{}{
+    nil, request, (*ExecuteRequest)(nil),
+  } {
+    err := msg.ContentAs(target)
+    assert.IsType(t, &InvalidContentTargetError{}, err, "%#v", target)
+  }
+}
+
+func TestMissingOptionalFields(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  // requests with only their required fields are answered...
+  reply := request(t, shell, signer, "execute_request",
+    map[string]interface{}{ "code": "1" },
+  )
+  assert.Equal(t, "execute_reply", reply.Header.MsgType)
+  assert.Equal(t, "ok", reply.Content.(map[string]interface{})["status"])
+
+  reply = request(t, shell, signer, "complete_request",
+    map[string]interface{}{ "code": "fmt" },
+  )
+  assert.Equal(t, "complete_reply", reply.Header.MsgType)
+
+  // ... even a shutdown_request without restart
+  reply = request(t, shell, signer, "shutdown_request", map[string]interface{}{})
+  assert.Equal(t, "shutdown_reply", reply.Header.MsgType)
+  assert.Equal(t, false, reply.Content.(map[string]interface{})["restart"])
+
+  waitForStop(t, frontEnd.stopped, "Run did not return after a shutdown_request")
+}
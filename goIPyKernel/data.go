@@ -221,3 +221,23 @@ func StoreData_AddMetadata(
     aMimeMap[metaKey] = dataValue
   }
 }
+
+// Add the key/value pair to the Transient map of the Data object.
+//
+// Takes the Data object at `objId` from the IPyKernelStore and adds the 
+// key/value (for example a DisplayIDKey) to the Data's Transient map. 
+//
+func StoreData_AddTransient(
+  objId uint64,
+  key   string,
+  value string,
+) {
+  anObj := TheObjectStore.Get(objId)
+  if anObj != nil {
+    aSyncedDataObj := anObj.(*SyncedData)
+    aSyncedDataObj.Mutex.Lock()
+    defer aSyncedDataObj.Mutex.Unlock()
+
+    aSyncedDataObj.TKData.Transient[key] = value
+  }
+}
@@ -0,0 +1,86 @@
+package goIPyKernel
+
+import (
+  "errors"
+
+  "github.com/gofrs/uuid"
+)
+
+// An output displayed (by a display_data) with a display id, in its
+// Data's Transient map, can later be replaced in place (by an
+// update_display_data with the same display id), for example to show the
+// progress of a long running evaluation or a live chart.
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#update-display-data
+//
+
+// DisplayIDKey is the key of the display id in a Data's Transient map.
+//
+const DisplayIDKey = "display_id"
+
+// ErrNoDisplayID is returned when updating a display whose Data has no
+// display id.
+//
+var ErrNoDisplayID = errors.New("an update_display_data needs a display_id")
+
+// NewDisplayID returns a new (random) display id.
+//
+func NewDisplayID() (string, error) {
+  displayID, err := uuid.NewV4()
+  if err != nil {
+    return "", err
+  }
+  return displayID.String(), nil
+}
+
+// DisplayID returns the display id of the data (or "" if it has none).
+//
+func DisplayID(data Data) string {
+  displayID, _ := data.Transient[DisplayIDKey].(string)
+  return displayID
+}
+
+// WithDisplayID returns the data with the displayID added to (a copy of)
+// its Transient map.
+//
+func WithDisplayID(data Data, displayID string) Data {
+  transient := make(MIMEMap, len(data.Transient)+1)
+  for key, value := range data.Transient {
+    transient[key] = value
+  }
+  transient[DisplayIDKey] = displayID
+  data.Transient = transient
+  return data
+}
+
+// DisplayHandle displays, and then updates in place, the outputs with its
+// (display) ID.
+//
+type DisplayHandle struct {
+  ID      string
+  Receipt *MsgReceipt
+}
+
+// NewDisplayHandle creates a DisplayHandle, with a new display id, which
+// publishes through the receipt.
+//
+func (receipt *MsgReceipt) NewDisplayHandle() (*DisplayHandle, error) {
+  displayID, err := NewDisplayID()
+  if err != nil {
+    return nil, err
+  }
+  return &DisplayHandle{ ID: displayID, Receipt: receipt }, nil
+}
+
+// Display publishes (a new output of) the data with the handle's ID.
+//
+func (handle *DisplayHandle) Display(data Data) error {
+  return handle.Receipt.PublishDisplayData(WithDisplayID(data, handle.ID))
+}
+
+// Update replaces every output displayed with the handle's ID by the
+// data.
+//
+func (handle *DisplayHandle) Update(data Data) error {
+  return handle.Receipt.PublishUpdateDisplayData(WithDisplayID(data, handle.ID))
+}
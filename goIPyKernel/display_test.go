@@ -0,0 +1,70 @@
+package goIPyKernel
+
+import(
+  "testing"
+
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestWithDisplayID(t *testing.T) {
+  data := Data{
+    Data:      MIMEMap{ MIMETypeText: "42" },
+    Transient: MIMEMap{ "other": "value" },
+  }
+  withID := WithDisplayID(data, "an id")
+  assert.Equal(t, "an id", DisplayID(withID))
+  assert.Equal(t, "value", withID.Transient["other"])
+  assert.Equal(t, "", DisplayID(data), "the original data was changed")
+
+  receipt := MsgReceipt{}
+  assert.Equal(t, ErrNoDisplayID, receipt.PublishUpdateDisplayData(data))
+}
+
+func TestDisplayHandles(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+  iopub := subscribeIOPub(t, frontEnd.connInfo, shell)
+  defer iopub.Close()
+
+  reply := request(t, shell, signer, "execute_request",
+    map[string]interface{}{ "code": "progress" },
+  )
+  assert.Equal(t, "execute_reply", reply.Header.MsgType)
+
+  displayed := nextIOPub(t, iopub, signer, "display_data")
+  content := displayed.Content.(map[string]interface{})
+  assert.Equal(t, map[string]interface{}{ MIMETypeText: "0%" }, content["data"])
+  transient := content["transient"].(map[string]interface{})
+  displayID, _ := transient[DisplayIDKey].(string)
+  assert.NotEmpty(t, displayID, "the display_data had no display_id")
+
+  updated := nextIOPub(t, iopub, signer, "update_display_data")
+  content = updated.Content.(map[string]interface{})
+  assert.Equal(t, map[string]interface{}{ MIMETypeText: "100%" }, content["data"])
+  assert.Equal(t,
+    map[string]interface{}{ DisplayIDKey: displayID },
+    content["transient"],
+  )
+}
+
+func TestPublishClearOutput(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+  iopub := subscribeIOPub(t, frontEnd.connInfo, shell)
+  defer iopub.Close()
+
+  reply := request(t, shell, signer, "execute_request",
+    map[string]interface{}{ "code": "clear" },
+  )
+  assert.Equal(t, "execute_reply", reply.Header.MsgType)
+
+  cleared := nextIOPub(t, iopub, signer, "clear_output")
+  assert.Equal(t,
+    map[string]interface{}{ "wait": true },
+    cleared.Content,
+  )
+}
@@ -0,0 +1,84 @@
+package goIPyKernel
+
+import (
+  "errors"
+  "strings"
+)
+
+// The ANSI escape sequences used to colour tracebacks (the notebook
+// renders these colours in its error output).
+//
+const (
+  ANSIReset   = "\x1b[0m"
+  ANSIBoldRed = "\x1b[1;31m"
+  ANSIGreen   = "\x1b[0;32m"
+)
+
+// ExecutionError is an error, returned by an adaptor's EvaluateCode (or
+// other) method, which describes an exception in the user's code in
+// terms of Jupyter's `ename`, `evalue` and `traceback`.
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#execution-errors
+//
+type ExecutionError struct {
+
+  // Name is the exception's (class or type) name, for example "NameError".
+  //
+  Name string
+
+  // Value is the exception's message.
+  //
+  Value string
+
+  // Traceback lists the (uncoloured) stack frames, innermost first, at
+  // which the exception occurred. It may be empty.
+  //
+  Traceback []string
+}
+
+// NewExecutionError creates an ExecutionError.
+//
+func NewExecutionError(name, value string, traceback []string) *ExecutionError {
+  return &ExecutionError{
+    Name:      name,
+    Value:     value,
+    Traceback: traceback,
+  }
+}
+
+// Error implements the error interface.
+//
+func (err *ExecutionError) Error() string {
+  if err.Name == "" {
+    return err.Value
+  }
+  return err.Name + ": " + err.Value
+}
+
+// ColouredTraceback returns the traceback as the front-end should show it:
+// a (bold red) `Name: Value` header followed by the (green) stack frames.
+//
+func (err *ExecutionError) ColouredTraceback() []string {
+  traceback := make([]string, 0, len(err.Traceback)+1)
+  header    := ANSIBoldRed + err.Name + ANSIReset
+  if err.Value != "" {
+    header = header + ": " + err.Value
+  }
+  traceback = append(traceback, header)
+  for _, frame := range err.Traceback {
+    traceback = append(traceback, "  " + ANSIGreen + frame + ANSIReset)
+  }
+  return traceback
+}
+
+// AsExecutionError returns the ExecutionError in err's chain, or an
+// ExecutionError named "ERROR" (whose Value is err's message) if there is
+// none.
+//
+func AsExecutionError(err error) *ExecutionError {
+  var execErr *ExecutionError
+  if errors.As(err, &execErr) {
+    return execErr
+  }
+  return NewExecutionError("ERROR", strings.TrimSpace(err.Error()), nil)
+}
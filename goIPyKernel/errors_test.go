@@ -0,0 +1,33 @@
+package goIPyKernel
+
+import(
+  "errors"
+  "fmt"
+  "testing"
+
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestAsExecutionError(t *testing.T) {
+  execErr := NewExecutionError("NameError", "undefined x", []string{"(cell):1"})
+  assert.Equal(t, "NameError: undefined x", execErr.Error())
+
+  wrapped := fmt.Errorf("evaluating: %w", execErr)
+  assert.Same(t, execErr, AsExecutionError(wrapped), "should unwrap the ExecutionError")
+
+  plain := AsExecutionError(errors.New(" something failed\n"))
+  assert.Equal(t, "ERROR", plain.Name)
+  assert.Equal(t, "something failed", plain.Value)
+  assert.Empty(t, plain.Traceback)
+}
+
+func TestColouredTraceback(t *testing.T) {
+  execErr := NewExecutionError("NameError", "undefined x", []string{"(cell):1", "(cell):3"})
+  assert.Equal(t, []string{
+    ANSIBoldRed + "NameError" + ANSIReset + ": undefined x",
+    "  " + ANSIGreen + "(cell):1" + ANSIReset,
+    "  " + ANSIGreen + "(cell):3" + ANSIReset,
+  }, execErr.ColouredTraceback())
+}
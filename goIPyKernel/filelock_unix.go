@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package goIPyKernel
+
+import (
+  "os"
+  "syscall"
+)
+
+// lockFile takes an exclusive (advisory) lock on the file, waiting until 
+// any other process holding a lock on it releases its lock. 
+//
+func lockFile(file *os.File) error {
+  return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+// unlockFile releases the lock taken by lockFile. 
+//
+func unlockFile(file *os.File) error {
+  return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
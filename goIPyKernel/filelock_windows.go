@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package goIPyKernel
+
+import (
+  "os"
+)
+
+// lockFile does nothing on Windows (where the history file's appends are 
+// relied upon instead). 
+//
+func lockFile(file *os.File) error {
+  return nil
+}
+
+// unlockFile does nothing on Windows. 
+//
+func unlockFile(file *os.File) error {
+  return nil
+}
@@ -0,0 +1,95 @@
+package goIPyKernel
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "time"
+
+  "github.com/go-zeromq/zmq4"
+  "golang.org/x/xerrors"
+)
+
+// In the kernel handshake pattern the kernel is not given its ports.
+// Instead it binds its sockets to random ports and then reports them (as
+// a JSON encoded ConnectionInfo) to the provisioner which launched it, by
+// sending them to the provisioner's REP socket and waiting for a reply.
+//
+// see: https://github.com/jupyter/enhancement-proposals/pull/66
+//
+
+// HandshakeTimeout is how long the kernel waits for the provisioner to
+// acknowledge the kernel's connection info.
+//
+var HandshakeTimeout = 30 * time.Second
+
+// BoundConnectionInfo returns the connInfo updated with the ports to which
+// the sockets are actually bound (for example after binding to random tcp
+// ports or to newly assigned ipc ports).
+//
+func BoundConnectionInfo(connInfo ConnectionInfo, sg SocketGroup) ConnectionInfo {
+  for _, bound := range []struct {
+    socket Socket
+    port   *int
+  }{
+    { sg.ShellSocket,   &connInfo.ShellPort },
+    { sg.ControlSocket, &connInfo.ControlPort },
+    { sg.StdinSocket,   &connInfo.StdinPort },
+    { sg.IOPubSocket,   &connInfo.IOPubPort },
+    { sg.HBSocket,      &connInfo.HBPort },
+  } {
+    if port := boundPort(bound.socket.Socket.Addr()); port != 0 {
+      *bound.port = port
+    }
+  }
+  return connInfo
+}
+
+// ReportConnectionInfo sends the (JSON encoded) connInfo to the
+// provisioner's REP socket listening on the handshakePort (of the
+// connInfo's IP) and waits (at most HandshakeTimeout) for its reply.
+//
+func ReportConnectionInfo(connInfo ConnectionInfo, handshakePort int) error {
+  if connInfo.Transport != "tcp" {
+    return fmt.Errorf(
+      "the kernel handshake needs the tcp transport (not %q)",
+      connInfo.Transport,
+    )
+  }
+
+  connData, err := json.Marshal(connInfo)
+  if err != nil {
+    return err
+  }
+
+  ctx, cancel := context.WithTimeout(context.Background(), HandshakeTimeout)
+  defer cancel()
+
+  provisioner := zmq4.NewReq(ctx)
+  defer provisioner.Close()
+
+  err = provisioner.Dial(fmt.Sprintf("tcp://%v:%v", connInfo.IP, handshakePort))
+  if err != nil {
+    return xerrors.Errorf("could not dial the handshake socket: %w", err)
+  }
+
+  acknowledged := make(chan error, 1)
+  go func() {
+    if err := provisioner.Send(zmq4.NewMsg(connData)); err != nil {
+      acknowledged <- err
+      return
+    }
+    _, err := provisioner.Recv()
+    acknowledged <- err
+  }()
+
+  select {
+  case err = <-acknowledged:
+    if err != nil {
+      return xerrors.Errorf("could not complete the kernel handshake: %w", err)
+    }
+    return nil
+  case <-ctx.Done():
+    return fmt.Errorf("the provisioner did not acknowledge the kernel handshake")
+  }
+}
@@ -0,0 +1,74 @@
+package goIPyKernel
+
+import(
+  "context"
+  "encoding/json"
+  "fmt"
+  "net"
+  "testing"
+  "time"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestKernelHandshake(t *testing.T) {
+  connFile, connInfo := writeConnectionFile(t)
+  signer := testSigner(t, connInfo.Key)
+
+  provisioner := zmq4.NewRep(context.Background())
+  defer provisioner.Close()
+  err := provisioner.Listen("tcp://127.0.0.1:0")
+  assert.NoError(t, err, "could not listen on the handshake socket")
+
+  kernel := NewIPyKernel(newTestAdaptor())
+  kernel.History, _ = OpenHistoryFile(tempHistoryPath(t))
+  kernel.HandshakePort = provisioner.Addr().(*net.TCPAddr).Port
+  _, stop := runTestKernel(t, kernel, connFile)
+  defer stop()
+
+  // the kernel reports the (random) ports to which it is bound...
+  handshake, err := provisioner.Recv()
+  assert.NoError(t, err, "could not receive the kernel's connection info")
+  var reported ConnectionInfo
+  err = json.Unmarshal(handshake.Bytes(), &reported)
+  assert.NoError(t, err, "could not decode the kernel's connection info")
+  assert.Equal(t, connInfo.Key, reported.Key)
+  assert.Equal(t, connInfo.SignatureScheme, reported.SignatureScheme)
+  for _, port := range []int{
+    reported.ShellPort, reported.ControlPort, reported.StdinPort,
+    reported.IOPubPort, reported.HBPort,
+  } {
+    assert.NotZero(t, port, "a port was not reported")
+  }
+
+  // ... and, once the provisioner replies, publishes its starting status
+  iopub := zmq4.NewSub(context.Background())
+  defer iopub.Close()
+  err = iopub.Dial(fmt.Sprintf("tcp://127.0.0.1:%d", reported.IOPubPort))
+  assert.NoError(t, err, "could not dial the iopub socket")
+  err = iopub.SetOption(zmq4.OptionSubscribe, "")
+  assert.NoError(t, err, "could not subscribe to the iopub socket")
+  time.Sleep(200 * time.Millisecond)
+
+  err = provisioner.Send(zmq4.NewMsgString("ok"))
+  assert.NoError(t, err, "could not acknowledge the handshake")
+
+  status := nextIOPub(t, iopub, signer, "status")
+  assert.Equal(t,
+    map[string]interface{}{ "execution_state": KernelStarting },
+    status.Content,
+  )
+
+  shell := dialTestSocket(t, "shell", reported.ShellPort)
+  defer shell.Close()
+  reply := request(t, shell, signer, "kernel_info_request", nil)
+  assert.Equal(t, "kernel_info_reply", reply.Header.MsgType)
+}
+
+func TestReportConnectionInfoNeedsTCP(t *testing.T) {
+  err := ReportConnectionInfo(ConnectionInfo{ Transport: "ipc" }, 1234)
+  assert.Error(t, err, "the handshake should need tcp")
+}
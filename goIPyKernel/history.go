@@ -0,0 +1,318 @@
+package goIPyKernel
+
+import (
+  "bufio"
+  "encoding/json"
+  "os"
+  "path/filepath"
+  "regexp"
+  "runtime"
+  "strings"
+  "sync"
+)
+
+// HistoryEntry records one (stored) execution of user code.
+//
+type HistoryEntry struct {
+
+  // Session counts up each time a kernel (using this HistoryStore) starts
+  // or is restarted.
+  //
+  Session int `json:"session"`
+
+  // Line is the execution count of the code within its session.
+  //
+  Line int `json:"line"`
+
+  // Input is the code executed.
+  //
+  Input string `json:"input"`
+
+  // Output is the (text/plain) result of the execution (if any).
+  //
+  Output string `json:"output,omitempty"`
+}
+
+// HistoryStore is a persistent (JSON lines) file of HistoryEntries, one
+// file per kernel implementation, used to answer history_requests.
+//
+// The whole history is read when the store is opened, each new entry is
+// appended to the file as it is added.
+//
+// Several kernels may share a history file, so the file is (advisory)
+// locked while a session is chosen and while entries are appended. Each
+// session is claimed by appending a session marker (an entry with a line
+// of zero and no input) to the file, so that a kernel opening the file
+// later chooses a later session.
+//
+type HistoryStore struct {
+  Mutex   sync.Mutex
+  Path    string
+  Session int
+  Entries []HistoryEntry
+}
+
+// HistoryDir returns the directory, in the user's data directory, in which
+// the HistoryStores are kept.
+//
+// The user's data directory is $XDG_DATA_HOME (if set), otherwise
+// ~/Library/Application Support on macOS, %APPDATA% on Windows and
+// ~/.local/share elsewhere.
+//
+func HistoryDir() (string, error) {
+  dataDir := os.Getenv("XDG_DATA_HOME")
+  if dataDir == "" {
+    switch runtime.GOOS {
+      case "windows" :
+        dataDir = os.Getenv("APPDATA")
+      case "darwin" :
+        homeDir, err := os.UserHomeDir()
+        if err != nil {
+          return "", err
+        }
+        dataDir = filepath.Join(homeDir, "Library", "Application Support")
+    }
+  }
+  if dataDir == "" {
+    homeDir, err := os.UserHomeDir()
+    if err != nil {
+      return "", err
+    }
+    dataDir = filepath.Join(homeDir, ".local", "share")
+  }
+  return filepath.Join(dataDir, "goIPyKernel", "history"), nil
+}
+
+// OpenHistoryStore opens the HistoryStore of the kernel implementation
+// (see KernelInfo) in the HistoryDir and starts a new session.
+//
+func OpenHistoryStore(implementation string) (*HistoryStore, error) {
+  historyDir, err := HistoryDir()
+  if err != nil {
+    return nil, err
+  }
+  return OpenHistoryFile(filepath.Join(historyDir, implementation + ".jsonl"))
+}
+
+// OpenHistoryFile opens (creating, if need be) the HistoryStore kept in
+// the file at `path` and starts a new session.
+//
+func OpenHistoryFile(path string) (*HistoryStore, error) {
+  store := &HistoryStore{ Path: path }
+
+  err := store.withLockedFile(func(historyFile *os.File) error {
+    entries, err := store.claimNextSession(historyFile)
+    store.Entries = entries
+    return err
+  })
+  if err != nil {
+    return nil, err
+  }
+  return store, nil
+}
+
+// NewSession starts a new session (for example when the adaptor is
+// restarted), following the last session of any kernel sharing the
+// history file. (If the history file can not be read, the new session
+// simply follows the current session).
+//
+func (store *HistoryStore) NewSession() error {
+  store.Mutex.Lock()
+  defer store.Mutex.Unlock()
+
+  err := store.withLockedFile(func(historyFile *os.File) error {
+    _, err := store.claimNextSession(historyFile)
+    return err
+  })
+  if err != nil {
+    store.Session++
+  }
+  return err
+}
+
+// withLockedFile opens (creating, if need be) the history file and calls
+// `use` while holding an exclusive lock on the file.
+//
+func (store *HistoryStore) withLockedFile(use func(*os.File) error) error {
+  if err := os.MkdirAll(filepath.Dir(store.Path), 0700); err != nil {
+    return err
+  }
+  historyFile, err := os.OpenFile(
+    store.Path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600,
+  )
+  if err != nil {
+    return err
+  }
+  defer historyFile.Close()
+
+  if err := lockFile(historyFile); err != nil {
+    return err
+  }
+  defer unlockFile(historyFile)
+
+  return use(historyFile)
+}
+
+// claimNextSession reads the (locked) history file, making the session
+// following its last session the current session (and claiming it with a
+// session marker). Returns the entries read (without any session
+// markers).
+//
+func (store *HistoryStore) claimNextSession(
+  historyFile *os.File,
+) ([]HistoryEntry, error) {
+  var entries []HistoryEntry
+  lastSession := 0
+
+  scanner := bufio.NewScanner(historyFile)
+  scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+  for scanner.Scan() {
+    var entry HistoryEntry
+    // ignore any (partially written) lines which can not be decoded
+    if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+      continue
+    }
+    if lastSession < entry.Session {
+      lastSession = entry.Session
+    }
+    if entry.Line != 0 {
+      entries = append(entries, entry)
+    }
+  }
+  if err := scanner.Err(); err != nil {
+    return nil, err
+  }
+
+  marker := HistoryEntry{ Session: lastSession + 1 }
+  if err := appendHistoryEntry(historyFile, marker); err != nil {
+    return nil, err
+  }
+  store.Session = marker.Session
+  return entries, nil
+}
+
+// appendHistoryEntry appends the entry (as one JSON line) to the history
+// file.
+//
+func appendHistoryEntry(historyFile *os.File, entry HistoryEntry) error {
+  entryJSON, err := json.Marshal(entry)
+  if err != nil {
+    return err
+  }
+  _, err = historyFile.Write(append(entryJSON, '\n'))
+  return err
+}
+
+// Add appends the execution of the `input` code (which produced the
+// `output`) as `line` of the current session.
+//
+func (store *HistoryStore) Add(line int, input, output string) error {
+  store.Mutex.Lock()
+  defer store.Mutex.Unlock()
+
+  entry := HistoryEntry{
+    Session: store.Session,
+    Line:    line,
+    Input:   input,
+    Output:  output,
+  }
+  err := store.withLockedFile(func(historyFile *os.File) error {
+    return appendHistoryEntry(historyFile, entry)
+  })
+  if err != nil {
+    return err
+  }
+
+  store.Entries = append(store.Entries, entry)
+  return nil
+}
+
+// Tail returns (at most) the last `n` entries.
+//
+func (store *HistoryStore) Tail(n int) []HistoryEntry {
+  store.Mutex.Lock()
+  defer store.Mutex.Unlock()
+  return lastEntries(store.Entries, n)
+}
+
+// Range returns the entries of the `session` whose lines are at least
+// `start` and (if `stop` is positive) less than `stop`. A `session` of
+// zero (or less) counts back from the current session.
+//
+func (store *HistoryStore) Range(session, start, stop int) []HistoryEntry {
+  store.Mutex.Lock()
+  defer store.Mutex.Unlock()
+
+  if session <= 0 {
+    session += store.Session
+  }
+  entries := []HistoryEntry{}
+  for _, entry := range store.Entries {
+    if entry.Session != session || entry.Line < start {
+      continue
+    }
+    if 0 < stop && stop <= entry.Line {
+      continue
+    }
+    entries = append(entries, entry)
+  }
+  return entries
+}
+
+// Search returns (at most) the last `n` entries (all entries if `n` is
+// zero or less) whose input matches the glob `pattern` (in which `*`
+// matches any string and `?` any character). If `unique` is true only
+// the last entry with any given input is returned.
+//
+func (store *HistoryStore) Search(pattern string, n int, unique bool) []HistoryEntry {
+  store.Mutex.Lock()
+  defer store.Mutex.Unlock()
+
+  globRegexp := globToRegexp(pattern)
+  entries := []HistoryEntry{}
+  seen    := make(map[string]bool)
+  for i := len(store.Entries) - 1; 0 <= i; i-- {
+    entry := store.Entries[i]
+    if !globRegexp.MatchString(entry.Input) {
+      continue
+    }
+    if unique {
+      if seen[entry.Input] {
+        continue
+      }
+      seen[entry.Input] = true
+    }
+    entries = append(entries, entry)
+    if 0 < n && n <= len(entries) {
+      break
+    }
+  }
+
+  // we searched backwards, so return the entries in their original order
+  for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+    entries[i], entries[j] = entries[j], entries[i]
+  }
+  return entries
+}
+
+// lastEntries returns (at most) the last `n` of the entries.
+//
+func lastEntries(entries []HistoryEntry, n int) []HistoryEntry {
+  if n < 0 {
+    n = 0
+  }
+  if len(entries) < n {
+    n = len(entries)
+  }
+  return append([]HistoryEntry{}, entries[len(entries)-n:]...)
+}
+
+// globToRegexp converts a glob pattern (which must match the whole
+// string) into a regular expression.
+//
+func globToRegexp(pattern string) *regexp.Regexp {
+  quoted := regexp.QuoteMeta(pattern)
+  quoted  = strings.Replace(quoted, `\*`, `.*`, -1)
+  quoted  = strings.Replace(quoted, `\?`, `.`, -1)
+  return regexp.MustCompile(`(?s)^` + quoted + `$`)
+}
@@ -0,0 +1,111 @@
+package goIPyKernel
+
+import(
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+// tempHistoryPath returns the path of a (not yet existing) history file in
+// a temporary directory.
+//
+func tempHistoryPath(t *testing.T) string {
+  dir, err := ioutil.TempDir("", "goIPyKernel")
+  assert.NoError(t, err, "could not create a temporary directory")
+  t.Cleanup(func() { os.RemoveAll(dir) })
+  return filepath.Join(dir, "history", "test.jsonl")
+}
+
+func TestHistoryStoreSessions(t *testing.T) {
+  path := tempHistoryPath(t)
+
+  store, err := OpenHistoryFile(path)
+  assert.NoError(t, err, "could not open a new history file")
+  assert.Equal(t, 1, store.Session)
+  assert.NoError(t, store.Add(1, "a := 1", ""))
+  assert.NoError(t, store.Add(2, "a", "1"))
+  assert.NoError(t, store.NewSession())
+  assert.NoError(t, store.Add(1, "b := 2", ""))
+
+  // a reopened store continues from the last session in the file
+  store, err = OpenHistoryFile(path)
+  assert.NoError(t, err, "could not reopen the history file")
+  assert.Equal(t, 3, store.Session)
+  assert.Equal(t, []HistoryEntry{
+    { Session: 1, Line: 1, Input: "a := 1" },
+    { Session: 1, Line: 2, Input: "a", Output: "1" },
+    { Session: 2, Line: 1, Input: "b := 2" },
+  }, store.Entries)
+}
+
+func TestHistoryStoresShareSessions(t *testing.T) {
+  path := tempHistoryPath(t)
+
+  // kernels sharing a history file never share a session...
+  first, err := OpenHistoryFile(path)
+  assert.NoError(t, err, "could not open a new history file")
+  second, err := OpenHistoryFile(path)
+  assert.NoError(t, err, "could not open the history file again")
+  assert.Equal(t, 1, first.Session)
+  assert.Equal(t, 2, second.Session)
+
+  assert.NoError(t, first.NewSession())
+  assert.Equal(t, 3, first.Session)
+  assert.NoError(t, second.Add(1, "second", ""))
+  assert.NoError(t, first.Add(1, "first", ""))
+
+  // ... and none of their entries are lost
+  store, err := OpenHistoryFile(path)
+  assert.NoError(t, err, "could not reopen the history file")
+  assert.Equal(t, 4, store.Session)
+  assert.Equal(t, []HistoryEntry{
+    { Session: 2, Line: 1, Input: "second" },
+    { Session: 3, Line: 1, Input: "first" },
+  }, store.Entries)
+}
+
+func TestHistoryStoreQueries(t *testing.T) {
+  store, err := OpenHistoryFile(tempHistoryPath(t))
+  assert.NoError(t, err, "could not open a new history file")
+  assert.NoError(t, store.Add(1, "fmt.Println(1)", ""))
+  assert.NoError(t, store.Add(2, "x := 2", ""))
+  assert.NoError(t, store.NewSession())
+  assert.NoError(t, store.Add(1, "fmt.Println(1)", ""))
+  assert.NoError(t, store.Add(2, "y := 3", ""))
+  assert.NoError(t, store.Add(3, "fmt.Println(y)", ""))
+
+  tail := store.Tail(2)
+  assert.Equal(t, []string{ "y := 3", "fmt.Println(y)" }, historyInputs(tail))
+  assert.Len(t, store.Tail(10), 5)
+
+  // sessions of zero (or less) count back from the current session
+  assert.Equal(t, []string{ "y := 3" }, historyInputs(store.Range(0, 2, 3)))
+  assert.Equal(t, []string{ "x := 2" }, historyInputs(store.Range(-1, 2, 0)))
+  assert.Equal(t, []string{ "fmt.Println(1)", "x := 2" },
+    historyInputs(store.Range(1, 0, 0)),
+  )
+
+  assert.Equal(t, []string{ "fmt.Println(1)", "fmt.Println(1)", "fmt.Println(y)" },
+    historyInputs(store.Search("fmt.*", 0, false)),
+  )
+  assert.Equal(t, []string{ "fmt.Println(1)", "fmt.Println(y)" },
+    historyInputs(store.Search("fmt.*", 0, true)),
+  )
+  assert.Equal(t, []string{ "fmt.Println(y)" },
+    historyInputs(store.Search("fmt.Println(?)", 1, false)),
+  )
+  assert.Empty(t, store.Search("fmt", 0, false))
+}
+
+func historyInputs(entries []HistoryEntry) []string {
+  inputs := []string{}
+  for _, entry := range entries {
+    inputs = append(inputs, entry.Input)
+  }
+  return inputs
+}
@@ -0,0 +1,98 @@
+package goIPyKernel
+
+import (
+  "log"
+)
+
+// IOPub messages are published with a topic (their first frame) which
+// subscribers may use to filter the messages they receive: stream
+// messages have the topic `stream.<name>` (for example `stream.stdout`)
+// and all other messages `kernel.<kernel id>.<msg_type>`.
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#iopub-pub-sub
+//
+
+// IOPubTopic returns the topic of an IOPub message of msgType (with the
+// content) published by the kernel with the kernelID.
+//
+func IOPubTopic(kernelID, msgType string, content interface{}) string {
+  if msgType == "stream" {
+    switch stream := content.(type) {
+    case Stream:
+      return "stream." + stream.Name
+    case *Stream:
+      return "stream." + stream.Name
+    case map[string]interface{}:
+      if name, ok := stream["name"].(string); ok {
+        return "stream." + name
+      }
+    }
+  }
+  return "kernel." + kernelID + "." + msgType
+}
+
+// welcome sends an iopub_welcome message (with the subscribed topic) to
+// the subscriber, and only the subscriber, whose subscription the xpub
+// socket received (so that, whatever their subscription, subscribers
+// learn that their subscription is active).
+//
+func (receipt *MsgReceipt) welcome(
+  xpub *xpubSocket,
+  sub  xpubSubscription,
+  subscription string,
+) error {
+  msg, err := NewMsg("iopub_welcome", receipt.Msg)
+  if err != nil {
+    return err
+  }
+  msg.Content = IOPubWelcome{ Subscription: subscription }
+
+  wireMsg, err := receipt.toWireMsgWithIdentities(
+    [][]byte{ []byte(subscription) }, msg,
+  )
+  if err != nil {
+    return err
+  }
+  xpub.sendTo(sub.xconn, wireMsg)
+  return nil
+}
+
+// WatchIOPubSubscriptions welcomes each new subscriber to the (XPUB) IOPub
+// socket, until the socket is closed.
+//
+// An XPUB socket receives each subscription as a single frame: a 1 byte
+// followed by the subscribed topic (unsubscriptions start with a 0 byte).
+// (The IOPub socket is an xpubSocket, see NewXPubSocket, since zmq4's own
+// XPUB socket does not pass its subscriptions on to Recv, nor say which
+// subscriber sent them).
+//
+func WatchIOPubSubscriptions(sockets SocketGroup) {
+  xpub, ok := sockets.IOPubSocket.Socket.(*xpubSocket)
+  if !ok {
+    return
+  }
+  receipt := MsgReceipt{ Sockets: sockets }
+  for {
+    sub, err := xpub.recvSubscription()
+    if err != nil {
+      return
+    }
+    subscription, ok := subscribedTopic([][]byte{ sub.frame })
+    if !ok {
+      continue
+    }
+    if err := receipt.welcome(xpub, sub, subscription); err != nil {
+      log.Printf("Error sending iopub_welcome: %v\n", err)
+    }
+  }
+}
+
+// subscribedTopic returns the topic of an XPUB subscription message (or
+// false if the frames are not a subscription).
+//
+func subscribedTopic(frames [][]byte) (string, bool) {
+  if len(frames) != 1 || len(frames[0]) == 0 || frames[0][0] != 1 {
+    return "", false
+  }
+  return string(frames[0][1:]), true
+}
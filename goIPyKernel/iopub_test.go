@@ -0,0 +1,158 @@
+package goIPyKernel
+
+import(
+  "context"
+  "fmt"
+  "strings"
+  "testing"
+  "time"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestIOPubTopic(t *testing.T) {
+  assert.Equal(t, "stream.stdout", IOPubTopic("id", "stream", Stream{ Name: "stdout" }))
+  assert.Equal(t, "stream.stderr",
+    IOPubTopic("id", "stream", map[string]interface{}{ "name": "stderr" }),
+  )
+  assert.Equal(t, "kernel.id.status", IOPubTopic("id", "status", Status{}))
+  assert.Equal(t, "kernel.id.comm_msg", IOPubTopic("id", "comm_msg", nil))
+}
+
+func TestSubscribedTopic(t *testing.T) {
+  topic, ok := subscribedTopic([][]byte{ []byte("\x01kernel.") })
+  assert.True(t, ok)
+  assert.Equal(t, "kernel.", topic)
+
+  topic, ok = subscribedTopic([][]byte{ []byte("\x01") })
+  assert.True(t, ok)
+  assert.Equal(t, "", topic)
+
+  _, ok = subscribedTopic([][]byte{ []byte("\x00kernel.") })
+  assert.False(t, ok, "unsubscriptions are not subscriptions")
+  _, ok = subscribedTopic([][]byte{ []byte("\x01a"), []byte("b") })
+  assert.False(t, ok, "subscriptions have a single frame")
+  _, ok = subscribedTopic([][]byte{ {} })
+  assert.False(t, ok, "subscriptions are not empty")
+}
+
+func TestXPubConnDropsMessagesBeyondItsHighWaterMark(t *testing.T) {
+  xconn := &xpubConn{ queue: make(chan zmq4.Msg, 1) }
+
+  // a stalled subscriber never blocks the sender
+  xconn.enqueue(zmq4.NewMsgString("first"))
+  xconn.enqueue(zmq4.NewMsgString("dropped"))
+  assert.Equal(t, 1, len(xconn.queue))
+  assert.Equal(t, "first", string((<-xconn.queue).Frames[0]))
+}
+
+func TestIOPubTopicFrames(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  iopub := subscribeIOPub(t, frontEnd.connInfo, shell)
+  defer iopub.Close()
+
+  // messages are published with their topic (rather than the identities
+  // of the request's sender)
+  request(t, shell, signer, "kernel_info_request", nil)
+  for {
+    wireMsg, err := iopub.Recv()
+    assert.NoError(t, err, "could not receive from iopub")
+    msg, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+    assert.NoError(t, err, "could not decode the iopub message")
+    if msg.Header.MsgType != "status" {
+      continue
+    }
+    topic := string(wireMsg.Frames[0])
+    assert.True(t, strings.HasPrefix(topic, "kernel."), topic)
+    assert.True(t, strings.HasSuffix(topic, ".status"), topic)
+    assert.Equal(t, "<IDS|MSG>", string(wireMsg.Frames[1]))
+    return
+  }
+}
+
+func TestXPubSocketPassesOnSubscriptions(t *testing.T) {
+  xpub := NewXPubSocket(context.Background())
+  defer xpub.Close()
+  err := xpub.Listen("tcp://127.0.0.1:0")
+  assert.NoError(t, err, "could not listen on the xpub socket")
+
+  sub := zmq4.NewSub(context.Background())
+  defer sub.Close()
+  err = sub.Dial("tcp://" + xpub.Addr().String())
+  assert.NoError(t, err, "could not dial the xpub socket")
+  err = sub.SetOption(zmq4.OptionSubscribe, "a.topic")
+  assert.NoError(t, err, "could not subscribe")
+
+  subscription, err := xpub.Recv()
+  assert.NoError(t, err, "could not receive the subscription")
+  topic, ok := subscribedTopic(subscription.Frames)
+  assert.True(t, ok, "not a subscription")
+  assert.Equal(t, "a.topic", topic)
+
+  // messages are only sent to the subscribers of (a prefix of) their topic
+  err = xpub.Send(zmq4.NewMsgFrom([]byte("other.topic"), []byte("skipped")))
+  assert.NoError(t, err)
+  err = xpub.Send(zmq4.NewMsgFrom([]byte("a.topic.more"), []byte("sent")))
+  assert.NoError(t, err)
+  msg, err := sub.Recv()
+  assert.NoError(t, err, "could not receive the message")
+  assert.Equal(t, "sent", string(msg.Frames[1]))
+
+  xpub.Close()
+  _, err = xpub.Recv()
+  assert.Error(t, err, "Recv should fail once the socket is closed")
+}
+
+func TestKernelWelcomesIOPubSubscribers(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  // welcomed waits for the subscriber's welcome (which arrives without
+  // any shell request being sent)
+  welcomed := func(iopub zmq4.Socket) {
+    welcome := make(chan ComposedMsg, 1)
+    go func() { welcome <- nextIOPub(t, iopub, signer, "iopub_welcome") }()
+    select {
+    case msg := <-welcome:
+      assert.Equal(t,
+        map[string]interface{}{ "subscription": "" },
+        msg.Content,
+      )
+    case <-time.After(10 * time.Second):
+      t.Fatal("the subscriber was not welcomed")
+    }
+  }
+  subscribe := func() zmq4.Socket {
+    iopub := zmq4.NewSub(context.Background())
+    err := iopub.Dial(
+      fmt.Sprintf("tcp://127.0.0.1:%d", frontEnd.connInfo.IOPubPort),
+    )
+    assert.NoError(t, err, "could not dial the iopub socket")
+    err = iopub.SetOption(zmq4.OptionSubscribe, "")
+    assert.NoError(t, err, "could not subscribe to the iopub socket")
+    return iopub
+  }
+
+  first := subscribe()
+  defer first.Close()
+  welcomed(first)
+  second := subscribe()
+  defer second.Close()
+  welcomed(second)
+
+  // only the new subscriber is welcomed, so the first subscriber's next
+  // message is the status of the next request
+  request(t, shell, signer, "kernel_info_request", nil)
+  wireMsg, err := first.Recv()
+  assert.NoError(t, err, "could not receive from iopub")
+  msg, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+  assert.NoError(t, err, "could not decode the iopub message")
+  assert.Equal(t, "status", msg.Header.MsgType)
+}
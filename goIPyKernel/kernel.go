@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/go-zeromq/zmq4"
+	"github.com/gofrs/uuid"
 	"golang.org/x/xerrors"
 )
 
@@ -36,14 +37,38 @@ type Socket struct {
 }
 
 // SocketGroup holds the sockets needed to communicate with the kernel,
-// and the key for message signing.
+// the signer for message signing, the cache of recently seen signatures and
+// the kernel's id (used in the topics of IOPub messages).
 type SocketGroup struct {
 	ShellSocket   Socket
 	ControlSocket Socket
 	StdinSocket   Socket
 	IOPubSocket   Socket
 	HBSocket      Socket
-	Key           []byte
+	Signer        *MsgSigner
+	Replays       *ReplayCache
+	KernelID      string
+}
+
+// decodeWireMsg decodes (and verifies the signature of) a message received
+// on one of the sockets, rejecting any message whose signature has been
+// seen recently. A message whose content is a JSON object which does not
+// fit its msg_type's struct (for example `{"code": 5}`) is passed on
+// untyped, so that its handler can answer it with an error reply.
+//
+func (sg SocketGroup) decodeWireMsg(frames [][]byte) (ComposedMsg, [][]byte, error) {
+  msg, ids, err := WireMsgToTypedMsg(frames, sg.Signer)
+  var jsonErr *InvalidJSONError
+  if xerrors.As(err, &jsonErr) && jsonErr.Frame == "content" {
+    msg, ids, err = WireMsgToComposedMsg(frames, sg.Signer)
+  }
+  if err != nil || sg.Replays == nil || !sg.Signer.Signs() {
+    return msg, ids, err
+  }
+  if err := sg.Replays.Check(frames[len(ids)+1]); err != nil {
+    return msg, nil, err
+  }
+  return msg, ids, nil
 }
 
 // KernelLanguageInfo holds information about the language that this kernel executes code in.
@@ -64,13 +89,16 @@ type HelpLink struct {
 }
 
 // KernelInfo holds information about the igo kernel, for kernel_info_reply messages.
+// The Status, ProtocolVersion and Debugger fields are filled in by the kernel.
 type KernelInfo struct {
+	Status                string             `json:"status"`
 	ProtocolVersion       string             `json:"protocol_version"`
 	Implementation        string             `json:"implementation"`
 	ImplementationVersion string             `json:"implementation_version"`
 	LanguageInfo          KernelLanguageInfo `json:"language_info"`
 	Banner                string             `json:"banner"`
 	HelpLinks             []HelpLink         `json:"help_links"`
+	Debugger              bool               `json:"debugger"`
 }
 
 // shutdownReply encodes a boolean indication of shutdown/restart.
@@ -124,6 +152,126 @@ type AdaptorImpl interface {
 
 }
 
+// The (optional) Interface that an adaptor MAY implement to provide
+// introspection (for example the notebook's Shift-Tab help) of the code
+// under the cursor.
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#introspection
+//
+type AdaptorInspector interface {
+
+  // Inspect the object at cursorPos in the code and return what ever
+  // information is known about it as a Data object. The detailLevel is
+  // either 0 or 1 (where 1 asks for more information, such as source
+  // code). An empty Data object signals that nothing was found.
+  //
+  InspectCode(code string, cursorPos int, detailLevel int) (Data, error)
+}
+
+// ExpressionResult is the result of evaluating one of an execute_request's 
+// user_expressions: either the expression's value (as a Data object) or 
+// the error which evaluating it raised. 
+//
+type ExpressionResult struct {
+  Data Data
+  Err  error
+}
+
+// The (optional) Interface that an adaptor MAY implement to evaluate the 
+// user_expressions of an execute_request (used, for example, by variable 
+// watchers). 
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#execute
+//
+type AdaptorExpressionEvaluator interface {
+
+  // Evaluate each of the (named) expressions, after the execute_request's 
+  // code has been successfully evaluated, and return the result of each 
+  // (by the same name). The expressions should not have side effects and 
+  // an expression's error does not fail the execute_request. 
+  //
+  EvaluateUserExpressions(expressions map[string]string) map[string]ExpressionResult
+}
+
+// The possible code completeness states returned by an 
+// AdaptorCompletenessChecker. 
+//
+const (
+  CodeComplete   = "complete"
+  CodeIncomplete = "incomplete"
+  CodeInvalid    = "invalid"
+  CodeUnknown    = "unknown"
+)
+
+// The (optional) Interface that an adaptor MAY implement to tell
+// consoles (such as `jupyter console` and qtconsole) whether a cell is
+// finished or whether the user should be allowed to continue typing.
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#code-completeness
+//
+type AdaptorCompletenessChecker interface {
+
+  // Check the completeness of the code. The returned status MUST be one
+  // of CodeComplete, CodeIncomplete, CodeInvalid or CodeUnknown. When the
+  // code is incomplete, the indent is the string of white space the
+  // console should use to start the next line.
+  //
+  IsCodeComplete(code string) (status string, indent string)
+}
+
+// The (optional) Interface that an adaptor MAY implement to restart 
+// itself "in-process" when a front-end asks for a restart (a 
+// shutdown_request with restart=true). Without it the kernel shuts down 
+// and the front-end restarts the whole process. 
+//
+type AdaptorRestarter interface {
+
+  // Restart the adaptor by discarding all of the user's state (for example 
+  // by creating a new interpreter). Restart is called by Run, on Run's 
+  // goroutine, between shell messages. If Restart returns an error, the 
+  // kernel shuts down instead. 
+  //
+  Restart() error
+}
+
+// The (optional) Interface that an adaptor MAY implement to let its 
+// (or its user's) code open comms and register comm targets. 
+//
+type AdaptorCommRegistrar interface {
+
+  // RegisterComms gives the adaptor the kernel's CommManager. It is 
+  // called by Run before any messages are handled and again after each 
+  // (in-process) restart, once the CommManager has been Reset. 
+  //
+  RegisterComms(comms *CommManager)
+}
+
+// The (optional) Interface that an adaptor MAY implement to release any 
+// resources (such as an embedded interpreter) when the kernel shuts down. 
+//
+type AdaptorShutdowner interface {
+
+  // Shutdown the adaptor. Shutdown is called by Run, on Run's goroutine, 
+  // after all channel handlers have stopped and just before Run returns. 
+  //
+  Shutdown()
+}
+
+// The (optional) Interface that an adaptor MAY implement to allow users to 
+// interrupt (long running) evaluations. The kernel.json of such kernels 
+// should specify `"interrupt_mode": "message"`. 
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#kernel-interrupt
+//
+type AdaptorInterrupter interface {
+
+  // Interrupt the currently running EvaluateCode (if any) which should 
+  // then return an error. Interrupt is called from a different goroutine 
+  // than EvaluateCode and should do nothing if no evaluation is running. 
+  //
+  Interrupt()
+}
+
 type IPyKernel struct {
 
   // ExecCounter is incremented each time we run user code in the notebook. 
@@ -139,17 +287,167 @@ type IPyKernel struct {
   // Adaptor is this kernel's Adaptor Implementation.
   //
   Adaptor AdaptorImpl
+
+  // Comms manages the comms opened by (or with) the front-end. 
+  //
+  Comms *CommManager
+
+  // History records the code executed (with store_history) and answers 
+  // history_requests. If nil, Run opens the adaptor implementation's 
+  // HistoryStore (if it can). 
+  //
+  History *HistoryStore
+
+  // HandshakePort, if not zero, asks Run to use the kernel handshake: the 
+  // sockets are bound to random ports (the connection file's ports are 
+  // ignored) which are then reported to the provisioner listening on the 
+  // HandshakePort (see ReportConnectionInfo). 
+  //
+  HandshakePort int
+
+  // shellReceived numbers (from 1) the messages received on the shell 
+  // socket. When an execute_request with stop_on_error fails, abortUpTo 
+  // is set to the number of messages received when its reply was sent, 
+  // so that the execute_requests already queued behind it (those 
+  // numbered up to abortUpTo) are aborted. 
+  //
+  receivedLock  sync.Mutex
+  shellReceived uint64
+  abortUpTo     uint64
+
+  // interrupted is closed (and then replaced) by each interrupt, to wake 
+  // any evaluation waiting for input in MsgReceipt.ReadLine. 
+  //
+  interruptLock sync.Mutex
+  interrupted   chan struct{}
+
+  // shutdown is closed (once) to ask Run to shutdown the kernel.
+  //
+  shutdown     chan struct{}
+  shutdownOnce sync.Once
+
+  // restart asks Run to restart the adaptor (see AdaptorRestarter).
+  //
+  restart chan struct{}
 }
 
+// shellQueueLength is the number of shell messages which Run receives 
+// (and numbers) ahead of the one being handled, so that it knows which 
+// requests were queued before an execute_request failed. 
+//
+const shellQueueLength = 1000
+
 func NewIPyKernel(anAdaptor AdaptorImpl) *IPyKernel {
 
   return &IPyKernel{
     ExecCounter:    0,
     ExecSubCounter: 0,
     Adaptor:        anAdaptor,
+    Comms:          NewCommManager(),
+    interrupted:    make(chan struct{}),
+    shutdown:       make(chan struct{}),
+    restart:        make(chan struct{}, 1),
+  }
+}
+
+// receivedShellMsg numbers the next message received on the shell socket.
+//
+func (kernel *IPyKernel) receivedShellMsg() uint64 {
+  kernel.receivedLock.Lock()
+  defer kernel.receivedLock.Unlock()
+  kernel.shellReceived++
+  return kernel.shellReceived
+}
+
+// shellMsgsReceived returns the number of messages received (so far) on 
+// the shell socket.
+//
+func (kernel *IPyKernel) shellMsgsReceived() uint64 {
+  kernel.receivedLock.Lock()
+  defer kernel.receivedLock.Unlock()
+  return kernel.shellReceived
+}
+
+// interruptChannel returns the channel which the next interrupt closes.
+//
+func (kernel *IPyKernel) interruptChannel() <-chan struct{} {
+  kernel.interruptLock.Lock()
+  defer kernel.interruptLock.Unlock()
+  return kernel.interrupted
+}
+
+// interruptReadLines wakes any evaluation waiting for input (which 
+// ReadLine then returns ErrInterrupted). 
+//
+func (kernel *IPyKernel) interruptReadLines() {
+  kernel.interruptLock.Lock()
+  defer kernel.interruptLock.Unlock()
+  close(kernel.interrupted)
+  kernel.interrupted = make(chan struct{})
+}
+
+// RequestRestart asks Run to restart the adaptor "in-process" (once any 
+// shell message currently being handled has finished). Any running 
+// evaluation is interrupted. Has no effect if the adaptor does not 
+// implement the AdaptorRestarter interface. 
+//
+func (kernel *IPyKernel) RequestRestart() {
+  if _, ok := kernel.Adaptor.(AdaptorRestarter); !ok {
+    return
+  }
+  if interrupter, ok := kernel.Adaptor.(AdaptorInterrupter); ok {
+    interrupter.Interrupt()
+  }
+  kernel.interruptReadLines()
+  select {
+  case kernel.restart <- struct{}{}:
+  default: // a restart is already pending
+  }
+}
+
+// restartAdaptor resets the execution counters and restarts the adaptor 
+// (shutting down the kernel if the adaptor can not be restarted). 
+//
+func (kernel *IPyKernel) restartAdaptor() {
+  log.Println("Restarting in response to shutdown_request")
+  kernel.ExecCounter    = 0
+  kernel.ExecSubCounter = 0
+  if kernel.History != nil {
+    if err := kernel.History.NewSession(); err != nil {
+      log.Printf("Error starting a new history session: %v\n", err)
+    }
+  }
+  kernel.Comms.Reset()
+  if err := kernel.Adaptor.(AdaptorRestarter).Restart(); err != nil {
+    log.Printf("Error restarting the adaptor: %v\n", err)
+    kernel.RequestShutdown()
+    return
+  }
+  kernel.registerComms()
+}
+
+// registerComms gives the adaptor, if it implements the 
+// AdaptorCommRegistrar interface, the kernel's CommManager. 
+//
+func (kernel *IPyKernel) registerComms() {
+  if registrar, ok := kernel.Adaptor.(AdaptorCommRegistrar); ok {
+    registrar.RegisterComms(kernel.Comms)
   }
 }
 
+// RequestShutdown asks Run to shutdown the kernel (once any shell message 
+// currently being handled has finished). If the adaptor implements the 
+// AdaptorInterrupter interface, any running evaluation is interrupted. 
+//
+func (kernel *IPyKernel) RequestShutdown() {
+  kernel.shutdownOnce.Do(func() {
+    if interrupter, ok := kernel.Adaptor.(AdaptorInterrupter); ok {
+      interrupter.Interrupt()
+    }
+    close(kernel.shutdown)
+  })
+}
+
 // RunWithSocket invokes the `run` function after acquiring the 
 // `Socket.Lock` and releases the lock when done. 
 //
@@ -159,6 +457,14 @@ func (s *Socket) RunWithSocket(run func(socket zmq4.Socket) error) error {
 	return run(s.Socket)
 }
 
+// msgType holds a message (or error) received from a zmq socket.
+//
+type msgType struct {
+	Msg zmq4.Msg
+	Err error
+	Seq uint64 // the (shell) message's number, see IPyKernel.shellReceived
+}
+
 // IPyKernel::Run is the main entry point to start the kernel.
 //
 func (kernel *IPyKernel) Run(connectionFile string) {
@@ -166,14 +472,47 @@ func (kernel *IPyKernel) Run(connectionFile string) {
 	// Parse the connection info.
 	var connInfo ConnectionInfo
 
-	connData, err := ioutil.ReadFile(connectionFile)
-	if err != nil {
-		log.Fatal(err)
-	}
+  // Without a connection file the kernel runs standalone, choosing its 
+  // own ports and key. 
+  //
+  standalone := connectionFile == ""
+  if standalone {
+    newConnInfo, err := NewConnectionInfo()
+    if err != nil {
+      log.Fatal(err)
+    }
+    connInfo = newConnInfo
+  } else {
+    connData, err := ioutil.ReadFile(connectionFile)
+    if err != nil {
+      log.Fatal(err)
+    }
+
+    if err = json.Unmarshal(connData, &connInfo); err != nil {
+      log.Fatal(err)
+    }
+  }
 
-	if err = json.Unmarshal(connData, &connInfo); err != nil {
-		log.Fatal(err)
-	}
+  // Open the history (the kernel runs without history if it can not). 
+  //
+  if kernel.History == nil {
+    implementation := kernel.Adaptor.GetKernelInfo().Implementation
+    if history, err := OpenHistoryStore(implementation); err != nil {
+      log.Printf("Error opening the history store: %v\n", err)
+    } else {
+      kernel.History = history
+    }
+  }
+
+  // In the kernel handshake the sockets are bound to random ports. 
+  //
+  if kernel.HandshakePort != 0 {
+    connInfo.ShellPort   = 0
+    connInfo.ControlPort = 0
+    connInfo.StdinPort   = 0
+    connInfo.IOPubPort   = 0
+    connInfo.HBPort      = 0
+  }
 
 	// Set up the ZMQ sockets through which the kernel will communicate.
 	sockets, err := PrepareSockets(connInfo)
@@ -181,47 +520,142 @@ func (kernel *IPyKernel) Run(connectionFile string) {
 		log.Fatal(err)
 	}
 
-  // TODO connect all channel handlers to a WaitGroup to ensure shutdown 
-  // before returning from runKernel. 
+  if kernel.HandshakePort != 0 {
+    connInfo = BoundConnectionInfo(connInfo, sockets)
+    if err := ReportConnectionInfo(connInfo, kernel.HandshakePort); err != nil {
+      log.Fatal(err)
+    }
+  }
 
-  // Start up the heartbeat handler.
-	StartHeartbeat(sockets.HBSocket, &sync.WaitGroup{})
+  // A standalone kernel writes its connection file (removing it when the 
+  // kernel shuts down, or is stopped by a signal) and tells the user how 
+  // to connect a console to it. 
+  //
+  removeConnFile := func() {}
+  if standalone {
+    connInfo = BoundConnectionInfo(connInfo, sockets)
+    connFile, err := WriteConnectionFile(connInfo)
+    if err != nil {
+      log.Fatal(err)
+    }
+    removeConnFile = removeConnectionFileOnStop(connFile, kernel.RequestShutdown)
+    defer removeConnFile()
+    fmt.Printf(
+      "To connect a console to this kernel use:\n\n    jupyter console --existing %s\n\n",
+      connFile,
+    )
+  }
 
-  // TODO gracefully shutdown the heartbeat handler on kernel shutdown by 
-  // closing the chan returned by startHeartbeat. 
+  // Tell any (already subscribed) front-ends that the kernel is starting. 
+  //
+  starting := MsgReceipt{ Sockets: sockets }
+  if err := starting.PublishKernelStatus(KernelStarting); err != nil {
+    log.Printf("Error publishing kernel status 'starting': %v\n", err)
+  }
 
-  type msgType struct {
-		Msg zmq4.Msg
-		Err error
-	}
+  // Let comms publish (before any shell message is handled) and let the 
+  // adaptor register its comm targets. 
+  //
+  kernel.Comms.SetReceipt(MsgReceipt{ Sockets: sockets })
+  kernel.registerComms()
+
+  // All channel handlers are connected to the handlers WaitGroup to 
+  // ensure they have all stopped before Run returns. 
+  //
+  var handlers sync.WaitGroup
+
+  // Start up the heartbeat handler.
+	hbShutdown := StartHeartbeat(sockets.HBSocket, &handlers)
 
 	var (
-		shell = make(chan msgType)
+		shell = make(chan msgType, shellQueueLength)
 		stdin = make(chan msgType)
 		ctl   = make(chan msgType)
 		quit  = make(chan int)
 	)
 
-	defer close(quit)
-	poll := func(msgs chan msgType, sck zmq4.Socket) {
+  // When Run returns, stop all of the channel handlers, wait for them to 
+  // finish and then shutdown the adaptor and close the remaining sockets. 
+  //
+  defer func() {
+    close(quit)
+    close(hbShutdown)
+
+    handlers.Wait()
+
+    if shutdowner, ok := kernel.Adaptor.(AdaptorShutdowner); ok {
+      shutdowner.Shutdown()
+    }
+
+    sockets.ShellSocket.Socket.Close()
+    sockets.ControlSocket.Socket.Close()
+    sockets.StdinSocket.Socket.Close()
+    sockets.IOPubSocket.Socket.Close()
+    sockets.HBSocket.Socket.Close()
+  }()
+
+  // The poll goroutines (and the IOPub subscription watcher) are NOT 
+  // connected to the handlers WaitGroup, since (zmq4) Recv can block 
+  // forever on a socket to which no front-end ever connected (even once 
+  // the socket has been closed). They stop as soon as Recv returns. 
+  //
+  // The shell messages are numbered as they are received (see 
+  // IPyKernel.shellReceived). 
+  //
+	poll := func(msgs chan msgType, sck zmq4.Socket, numbered bool) {
 		defer close(msgs)
 		for {
 			msg, err := sck.Recv()
+			v := msgType{Msg: msg, Err: err}
+			if numbered && err == nil {
+				v.Seq = kernel.receivedShellMsg()
+			}
 			select {
-			case msgs <- msgType{Msg: msg, Err: err}:
+			case msgs <- v:
 			case <-quit:
 				return
 			}
 		}
 	}
 
-	go poll(shell, sockets.ShellSocket.Socket)
-	go poll(stdin, sockets.StdinSocket.Socket)
-	go poll(ctl, sockets.ControlSocket.Socket)
+	go poll(shell, sockets.ShellSocket.Socket, true)
+	go poll(stdin, sockets.StdinSocket.Socket, false)
+	go poll(ctl, sockets.ControlSocket.Socket, false)
+  go WatchIOPubSubscriptions(sockets)
 
-	// Start a message receiving loop.
+  // Forward any input_reply messages to the (blocked) evaluation waiting 
+  // for them in MsgReceipt.ReadLine. This can not be done in the message 
+  // receiving loop below, since that loop is itself blocked while the 
+  // evaluation runs. 
+  //
+  inputReplies := make(chan ComposedMsg, 1)
+  handlers.Add(1)
+  go func() {
+    defer handlers.Done()
+    forwardInputReplies(stdin, quit, inputReplies, sockets)
+  }()
+
+  // Handle the control messages in their own goroutine, so that they 
+  // (for example an interrupt_request) "jump ahead" of any shell message 
+  // currently being handled. 
+  //
+  handlers.Add(1)
+  go func() {
+    defer handlers.Done()
+    kernel.handleControlMsgs(ctl, quit, sockets)
+  }()
+
+	// Start a (shell) message receiving loop (until asked to shutdown).
 	for {
 		select {
+		case <-kernel.shutdown:
+			log.Println("Shutting down in response to shutdown_request")
+			removeConnFile()
+			return
+
+		case <-kernel.restart:
+			kernel.restartAdaptor()
+
 		case v := <-shell:
 			// Handle shell messages.
 			if v.Err != nil {
@@ -229,35 +663,114 @@ func (kernel *IPyKernel) Run(connectionFile string) {
 				continue
 			}
 
-			msg, ids, err := WireMsgToComposedMsg(v.Msg.Frames, sockets.Key)
-			if err != nil {
-				log.Println(err)
-				return
-			}
-
-			kernel.HandleShellMsg(MsgReceipt{msg, ids, sockets})
-
-		case <-stdin:
-			// TODO Handle stdin socket.
-			continue
-
-		case v := <-ctl:
-			if v.Err != nil {
-				log.Println(v.Err)
-				return
-			}
-
-			msg, ids, err := WireMsgToComposedMsg(v.Msg.Frames, sockets.Key)
+			// Malformed messages are logged and skipped.
+			msg, ids, err := sockets.decodeWireMsg(v.Msg.Frames)
 			if err != nil {
-				log.Println(err)
-				return
+				log.Printf("Skipping a malformed shell message: %v\n", err)
+				continue
 			}
 
-			kernel.HandleShellMsg(MsgReceipt{msg, ids, sockets})
+			kernel.HandleShellMsg(MsgReceipt{
+        Msg:          msg,
+        Identities:   ids,
+        Sockets:      sockets,
+        ReplySocket:  sockets.ShellSocket,
+        InputReplies: inputReplies,
+        Interrupted:  kernel.interruptChannel(),
+        ShuttingDown: kernel.shutdown,
+        received:     v.Seq,
+      })
 		}
 	}
 }
 
+// handleControlMsgs decodes and handles the messages received on the 
+// control socket (until the quit channel is closed). 
+//
+func (kernel *IPyKernel) handleControlMsgs(
+  ctl     <-chan msgType,
+  quit    <-chan int,
+  sockets SocketGroup,
+) {
+  for {
+    var v msgType
+    var ok bool
+    select {
+    case <-quit:
+      return
+    case v, ok = <-ctl:
+      if !ok {
+        return
+      }
+    }
+
+    if v.Err != nil {
+      log.Println(v.Err)
+      continue
+    }
+
+    msg, ids, err := sockets.decodeWireMsg(v.Msg.Frames)
+    if err != nil {
+      log.Printf("Skipping a malformed control message: %v\n", err)
+      continue
+    }
+
+    kernel.HandleControlMsg(MsgReceipt{
+      Msg:         msg,
+      Identities:  ids,
+      Sockets:     sockets,
+      ReplySocket: sockets.ControlSocket,
+    })
+  }
+}
+
+// forwardInputReplies decodes the messages received on the stdin socket 
+// and passes any input_reply messages on to the inputReplies channel 
+// (until the quit channel is closed). Replies which nobody is waiting for 
+// are dropped. 
+//
+func forwardInputReplies(
+  stdin        <-chan msgType,
+  quit         <-chan int,
+  inputReplies chan<- ComposedMsg,
+  sockets      SocketGroup,
+) {
+  for {
+    var v msgType
+    var ok bool
+    select {
+    case <-quit:
+      return
+    case v, ok = <-stdin:
+      if !ok {
+        return
+      }
+    }
+
+    if v.Err != nil {
+      log.Println(v.Err)
+      continue
+    }
+
+    msg, _, err := sockets.decodeWireMsg(v.Msg.Frames)
+    if err != nil {
+      log.Printf("Skipping a malformed stdin message: %v\n", err)
+      continue
+    }
+
+    if msg.Header.MsgType != "input_reply" {
+      log.Printf("Unexpected message on the stdin socket: %s\n", msg.Header.MsgType)
+      continue
+    }
+
+    select {
+    case inputReplies <- msg:
+    default:
+      log.Println("Dropping an input_reply which nobody is waiting for")
+    }
+  }
+}
+
 // prepareSockets sets up the ZMQ sockets through which the kernel
 // will communicate.
 //
@@ -269,6 +782,24 @@ func PrepareSockets(connInfo ConnectionInfo) (SocketGroup, error) {
 		ctx = context.Background()
 	)
 
+  // Create the message signer (before any sockets are opened, so that an
+  // unknown signature scheme fails cleanly).
+  //
+  sg.Signer, err = NewMsgSigner(connInfo.SignatureScheme, []byte(connInfo.Key))
+  if err != nil {
+    return sg, xerrors.Errorf("could not sign messages: %w", err)
+  }
+
+  // Check the transport (before any sockets are opened) and choose the 
+  // unassigned ports of ipc sockets. 
+  //
+  if _, err = Endpoint(connInfo, 0); err != nil {
+    return sg, err
+  }
+  if connInfo.Transport == "ipc" {
+    connInfo = AssignIPCPorts(connInfo)
+  }
+
   // Create the shell socket, a request-reply socket that may receive 
   // messages from multiple frontend for code execution, introspection, 
   // auto-completion, etc. 
@@ -291,9 +822,10 @@ func PrepareSockets(connInfo ConnectionInfo) (SocketGroup, error) {
 
   // Create the iopub socket, a publisher for broadcasting data like 
   // stdout/stderr output, displaying execution results or errors, kernel 
-  // status, etc. to connected subscribers. 
+  // status, etc. to connected subscribers. (An XPUB socket, so that 
+  // subscribers can be welcomed, see WatchIOPubSubscriptions). 
   //
-	sg.IOPubSocket.Socket = zmq4.NewPub(ctx)
+	sg.IOPubSocket.Socket = NewXPubSocket(ctx)
 	sg.IOPubSocket.Lock = &sync.Mutex{}
 
   // Create the heartbeat socket, a request-reply socket that only allows 
@@ -305,34 +837,43 @@ func PrepareSockets(connInfo ConnectionInfo) (SocketGroup, error) {
 	sg.HBSocket.Lock = &sync.Mutex{}
 
 	// Bind the sockets.
-	address := fmt.Sprintf("%v://%v:%%v", connInfo.Transport, connInfo.IP)
-	err = sg.ShellSocket.Socket.Listen(fmt.Sprintf(address, connInfo.ShellPort))
+	err = listenOn(sg.ShellSocket.Socket, connInfo, connInfo.ShellPort)
 	if err != nil {
 		return sg, xerrors.Errorf("could not listen on shell-socket: %w", err)
 	}
 
-	err = sg.ControlSocket.Socket.Listen(fmt.Sprintf(address, connInfo.ControlPort))
+	err = listenOn(sg.ControlSocket.Socket, connInfo, connInfo.ControlPort)
 	if err != nil {
 		return sg, xerrors.Errorf("could not listen on control-socket: %w", err)
 	}
 
-	err = sg.StdinSocket.Socket.Listen(fmt.Sprintf(address, connInfo.StdinPort))
+	err = listenOn(sg.StdinSocket.Socket, connInfo, connInfo.StdinPort)
 	if err != nil {
 		return sg, xerrors.Errorf("could not listen on stdin-socket: %w", err)
 	}
 
-	err = sg.IOPubSocket.Socket.Listen(fmt.Sprintf(address, connInfo.IOPubPort))
+	err = listenOn(sg.IOPubSocket.Socket, connInfo, connInfo.IOPubPort)
 	if err != nil {
 		return sg, xerrors.Errorf("could not listen on iopub-socket: %w", err)
 	}
 
-	err = sg.HBSocket.Socket.Listen(fmt.Sprintf(address, connInfo.HBPort))
+	err = listenOn(sg.HBSocket.Socket, connInfo, connInfo.HBPort)
 	if err != nil {
 		return sg, xerrors.Errorf("could not listen on hbeat-socket: %w", err)
 	}
 
-	// Set the message signing key.
-	sg.Key = []byte(connInfo.Key)
+  // Remember the signatures of recently received messages (to reject
+  // any replayed messages).
+  //
+  sg.Replays = NewReplayCache(DefaultReplayCacheSize)
+
+  // Identify this kernel in the topics of its IOPub messages.
+  //
+  kernelID, err := uuid.NewV4()
+  if err != nil {
+    return sg, err
+  }
+  sg.KernelID = kernelID.String()
 
 	return sg, nil
 }
@@ -350,18 +891,49 @@ func (kernel *IPyKernel) HandleShellMsg(receipt MsgReceipt) {
 		}
 	}()
 
+	// Any messages published by comms have this message as their parent.
+	kernel.Comms.SetReceipt(receipt)
+
 	switch receipt.Msg.Header.MsgType {
 	case "kernel_info_request":
 		if err := kernel.HandleKernelInfoRequest(receipt); err != nil {
-			log.Fatal(err)
+			log.Printf("Error handling kernel_info_request: %v\n", err)
+		}
+	case "comm_open":
+		if err := kernel.Comms.HandleCommOpen(receipt); err != nil {
+			log.Printf("Error handling comm_open: %v\n", err)
+		}
+	case "comm_msg":
+		if err := kernel.Comms.HandleCommMsg(receipt); err != nil {
+			log.Printf("Error handling comm_msg: %v\n", err)
+		}
+	case "comm_close":
+		if err := kernel.Comms.HandleCommClose(receipt); err != nil {
+			log.Printf("Error handling comm_close: %v\n", err)
+		}
+	case "comm_info_request":
+		if err := kernel.Comms.HandleCommInfoRequest(receipt); err != nil {
+			log.Printf("Error handling comm_info_request: %v\n", err)
 		}
 	case "complete_request":
 		if err := kernel.HandleCompleteRequest(receipt); err != nil {
-			log.Fatal(err)
+			log.Printf("Error handling complete_request: %v\n", err)
+		}
+	case "inspect_request":
+		if err := kernel.HandleInspectRequest(receipt); err != nil {
+			log.Printf("Error handling inspect_request: %v\n", err)
+		}
+	case "is_complete_request":
+		if err := kernel.HandleIsCompleteRequest(receipt); err != nil {
+			log.Printf("Error handling is_complete_request: %v\n", err)
 		}
 	case "execute_request":
 		if err := kernel.HandleExecuteRequest(receipt); err != nil {
-			log.Fatal(err)
+			log.Printf("Error handling execute_request: %v\n", err)
+		}
+	case "history_request":
+		if err := kernel.HandleHistoryRequest(receipt); err != nil {
+			log.Printf("Error handling history_request: %v\n", err)
 		}
 	case "shutdown_request":
 		kernel.HandleShutdownRequest(receipt)
@@ -370,50 +942,206 @@ func (kernel *IPyKernel) HandleShellMsg(receipt MsgReceipt) {
 	}
 }
 
+// HandleControlMsg responds to a message on the control ROUTER socket. 
+// 
+// Control messages are handled concurrently with (any long running) shell 
+// message, so only messages which are safe to handle while the adaptor is 
+// evaluating code are accepted here. 
+//
+func (kernel *IPyKernel) HandleControlMsg(receipt MsgReceipt) {
+  if err := receipt.PublishKernelStatus(KernelBusy); err != nil {
+    log.Printf("Error publishing kernel status 'busy': %v\n", err)
+  }
+  defer func() {
+    if err := receipt.PublishKernelStatus(KernelIdle); err != nil {
+      log.Printf("Error publishing kernel status 'idle': %v\n", err)
+    }
+  }()
+
+  switch receipt.Msg.Header.MsgType {
+  case "kernel_info_request":
+    if err := kernel.HandleKernelInfoRequest(receipt); err != nil {
+      log.Printf("Error handling kernel_info_request: %v\n", err)
+    }
+  case "interrupt_request":
+    if err := kernel.HandleInterruptRequest(receipt); err != nil {
+      log.Printf("Error handling interrupt_request: %v\n", err)
+    }
+  case "shutdown_request":
+    kernel.HandleShutdownRequest(receipt)
+  default:
+    log.Println("Unhandled control message: ", receipt.Msg.Header.MsgType)
+  }
+}
+
+// HandleKernelInfoRequest replies with the adaptor's KernelInfo (and the
+// protocol version negotiated with the front-end). Debugging is not
+// (yet) supported.
+//
 func (kernel *IPyKernel) HandleKernelInfoRequest(receipt MsgReceipt) error {
-	return receipt.Reply(
-    "kernel_info_reply",
-    kernel.Adaptor.GetKernelInfo(),
-  )
+  info := kernel.Adaptor.GetKernelInfo()
+  info.Status          = "ok"
+  info.ProtocolVersion = NegotiateVersion(receipt.Msg.Header.ProtocolVersion)
+  info.Debugger        = false
+  return receipt.Reply("kernel_info_reply", info)
 }
 
 func (kernel *IPyKernel) HandleCompleteRequest(receipt MsgReceipt) error {
 	// Extract the data from the request.
-	reqcontent := receipt.Msg.Content.(map[string]interface{})
-	code := reqcontent["code"].(string)
-	cursorPos := int(reqcontent["cursor_pos"].(float64))
+	var request CompleteRequest
+	if err := receipt.Msg.ContentAs(&request); err != nil {
+		return receipt.Reply("complete_reply", CompleteReply{
+			Status:       "error",
+			Matches:      []string{},
+			Metadata:     map[string]interface{}{},
+			ErrorContent: invalidContentError(err),
+		})
+	}
 
-	// autocomplete the code at the cursor position
+	// autocomplete the code at the cursor position (older clients count 
+	// cursor positions in UTF-16 code units rather than code points)
+	version := receipt.Msg.Header.ProtocolVersion
+	cursorPos := cursorFromClient(version, request.Code, request.CursorPos)
   cursorStart, cursorEnd, matches := 
-    kernel.Adaptor.GetCodeWordCompletions(code, cursorPos)
+    kernel.Adaptor.GetCodeWordCompletions(request.Code, cursorPos)
   
 	// prepare the reply
-	content := make(map[string]interface{})
+	content := CompleteReply{
+		Matches:  []string{},
+		Metadata: map[string]interface{}{},
+	}
 
 	if len(matches) == 0 {
-		content["ename"] = "ERROR"
-		content["evalue"] = "no completions found"
-		content["traceback"] = nil
-		content["status"] = "error"
+		content.Status = "error"
+		content.ErrorContent = &ErrorContent{
+			EName:     "ERROR",
+			EValue:    "no completions found",
+			Traceback: []string{},
+		}
 	} else {
-		content["cursor_start"] = float64(cursorStart)
-		content["cursor_end"] = float64(cursorEnd)
-		content["matches"] = matches
-		content["status"] = "ok"
+		content.Status = "ok"
+		content.CursorStart = cursorToClient(version, request.Code, cursorStart)
+		content.CursorEnd = cursorToClient(version, request.Code, cursorEnd)
+		content.Matches = matches
 	}
 
 	return receipt.Reply("complete_reply", content)
 }
 
+// HandleInspectRequest asks the adaptor, if it implements the
+// AdaptorInspector interface, for information about the code at the
+// cursor and sends an inspect_reply.
+//
+func (kernel *IPyKernel) HandleInspectRequest(receipt MsgReceipt) error {
+  // Extract the data from the request.
+  var request InspectRequest
+  if err := receipt.Msg.ContentAs(&request); err != nil {
+    return receipt.Reply("inspect_reply", InspectReply{
+      Status:       "error",
+      Data:         make(MIMEMap),
+      Metadata:     make(MIMEMap),
+      ErrorContent: invalidContentError(err),
+    })
+  }
+
+  // prepare the reply (by default nothing has been found)
+  content := InspectReply{
+    Status:   "ok",
+    Data:     make(MIMEMap),
+    Metadata: make(MIMEMap),
+  }
+
+  inspector, ok := kernel.Adaptor.(AdaptorInspector)
+  if !ok {
+    return receipt.Reply("inspect_reply", content)
+  }
+
+  data, err := inspector.InspectCode(
+    request.Code,
+    cursorFromClient(
+      receipt.Msg.Header.ProtocolVersion,
+      request.Code,
+      request.CursorPos,
+    ),
+    request.DetailLevel,
+  )
+  if err != nil {
+    content.Status = "error"
+    content.ErrorContent = NewErrorContent(AsExecutionError(err))
+  } else if len(data.Data) != 0 {
+    content.Found = true
+    content.Data = data.Data
+    content.Metadata = EnsureMIMEMap(data.Metadata)
+  }
+
+  return receipt.Reply("inspect_reply", content)
+}
+
+// HandleIsCompleteRequest asks the adaptor, if it implements the
+// AdaptorCompletenessChecker interface, whether the code is complete and
+// sends an is_complete_reply.
+//
+func (kernel *IPyKernel) HandleIsCompleteRequest(receipt MsgReceipt) error {
+  // Extract the data from the request.
+  // (the is_complete_reply has no "error" status, so a request which can
+  // not be decoded is answered with "unknown")
+  var request IsCompleteRequest
+  if err := receipt.Msg.ContentAs(&request); err != nil {
+    log.Printf("Error decoding is_complete_request: %v\n", err)
+    return receipt.Reply("is_complete_reply", IsCompleteReply{
+      Status: CodeUnknown,
+    })
+  }
+
+  // prepare the reply (by default we do not know)
+  content := IsCompleteReply{ Status: CodeUnknown }
+
+  checker, ok := kernel.Adaptor.(AdaptorCompletenessChecker)
+  if ok {
+    status, indent := checker.IsCodeComplete(request.Code)
+    content.Status = status
+    if status == CodeIncomplete {
+      content.Indent = indent
+    }
+  }
+
+  return receipt.Reply("is_complete_reply", content)
+}
+
 // handleExecuteRequest runs code from an execute_request method,
 // and sends the various reply messages.
 //
 func (kernel *IPyKernel) HandleExecuteRequest(receipt MsgReceipt) error {
 
 	// Extract the data from the request.
-	reqcontent := receipt.Msg.Content.(map[string]interface{})
-	code := reqcontent["code"].(string)
-	silent := reqcontent["silent"].(bool)
+	var request ExecuteRequest
+	if err := receipt.Msg.ContentAs(&request); err != nil {
+		return receipt.Reply("execute_reply", ExecuteReply{
+			Status:          "error",
+			ExecutionCount:  kernel.ExecCounter,
+			UserExpressions: map[string]UserExpression{},
+			ErrorContent:    invalidContentError(err),
+		})
+	}
+
+  // Abort any execute_request queued behind a failed one (see 
+  // stop_on_error below). 
+  //
+  if 0 < receipt.received && receipt.received <= kernel.abortUpTo {
+    return receipt.Reply("execute_reply", ExecuteReply{
+      Status:          "aborted",
+      ExecutionCount:  kernel.ExecCounter,
+      UserExpressions: map[string]UserExpression{},
+    })
+  }
+	code := request.Code
+	silent := request.Silent
+	receipt.AllowStdin = request.AllowStdin
+	receipt.Payloads = &Payloads{}
+
+	// silent executions are never stored in the history
+	storeHistory := request.StoreHistory && !silent
+	input := code
 
 	if !silent {
 		kernel.ExecCounter++
@@ -422,9 +1150,8 @@ func (kernel *IPyKernel) HandleExecuteRequest(receipt MsgReceipt) error {
 		kernel.ExecSubCounter++
   }
 
-	// Prepare the map that will hold the reply content.
-	content := make(map[string]interface{})
-	content["execution_count"] = kernel.ExecCounter
+	// Prepare the reply content.
+	content := ExecuteReply{ ExecutionCount: kernel.ExecCounter }
 
 	// Tell the front-end what the kernel is about to execute.
 	if err := receipt.PublishExecutionInput(kernel.ExecCounter, code); err != nil {
@@ -452,7 +1179,11 @@ func (kernel *IPyKernel) HandleExecuteRequest(receipt MsgReceipt) error {
 
 	jupyterStdOut := JupyterStreamWriter{StreamStdout, &receipt}
 	jupyterStdErr := JupyterStreamWriter{StreamStderr, &receipt}
-	outerr := OutErr{&jupyterStdOut, &jupyterStdErr}
+	outerr := OutErr{
+    Out: &jupyterStdOut,
+    Err: &jupyterStdErr,
+    In:  NewJupyterStdinReader(&receipt),
+  }
 
 	// Forward all data written to stdout/stderr to the front-end.
 	go func() {
@@ -488,11 +1219,18 @@ func (kernel *IPyKernel) HandleExecuteRequest(receipt MsgReceipt) error {
 	// Wait for the writers to finish forwarding the data.
 	writersWG.Wait()
 
+	if storeHistory && kernel.History != nil {
+		output, _ := data.Data[MIMETypeText].(string)
+		if err := kernel.History.Add(kernel.ExecCounter, input, output); err != nil {
+			log.Printf("Error storing history: %v\n", err)
+		}
+	}
+
 	if executionErr == nil {
 		// if the only non-nil value should be auto-rendered graphically, render it
 
-		content["status"] = "ok"
-		content["user_expressions"] = make(map[string]string)
+		content.Status = "ok"
+		content.UserExpressions = kernel.evaluateUserExpressions(request.UserExpressions)
 
 		if !silent && len(data.Data) != 0 {
 			// Publish the result of the execution.
@@ -501,39 +1239,185 @@ func (kernel *IPyKernel) HandleExecuteRequest(receipt MsgReceipt) error {
 			}
 		}
 	} else {
-		content["status"] = "error"
-		content["ename"] = "ERROR"
-		content["evalue"] = executionErr.Error()
-		content["traceback"] = nil
+		// adaptors may describe the error with an ExecutionError
+		content.Status = "error"
+		content.UserExpressions = map[string]UserExpression{}
+		content.ErrorContent = NewErrorContent(AsExecutionError(executionErr))
 
 		if err := receipt.PublishExecutionError(
-      executionErr.Error(),
-      []string{executionErr.Error()},
+      content.EName,
+      content.EValue,
+      content.Traceback,
     ); err != nil {
 			log.Printf("Error publishing execution error: %v\n", err)
 		}
 	}
 
-	// Send the output back to the notebook.
-	return receipt.Reply("execute_reply", content)
+	// Send the output (and any payloads) back to the notebook.
+	content.Payload = receipt.Payloads.List()
+	if err := receipt.Reply("execute_reply", content); err != nil {
+		return err
+	}
+
+  // As in IPython, a failed (non silent) execution with stop_on_error 
+  // aborts the execute_requests already queued behind it (for example the 
+  // remaining cells of a "Run All"). 
+  //
+  if content.Status == "error" && request.StopOnError && !silent {
+    kernel.abortUpTo = kernel.shellMsgsReceived()
+  }
+  return nil
+}
+
+// evaluateUserExpressions evaluates the (named) user_expressions of an 
+// execute_request using the adaptor's (optional) 
+// AdaptorExpressionEvaluator. Without one (or for expressions which are 
+// not strings) each expression's result is an error. 
+//
+func (kernel *IPyKernel) evaluateUserExpressions(
+  userExpressions map[string]interface{},
+) map[string]UserExpression {
+  results := make(map[string]UserExpression, len(userExpressions))
+  if len(userExpressions) == 0 {
+    return results
+  }
+
+  expressions := make(map[string]string, len(userExpressions))
+  for name, expression := range userExpressions {
+    if code, ok := expression.(string); ok {
+      expressions[name] = code
+    } else {
+      results[name] = NewUserExpression(ExpressionResult{
+        Err: fmt.Errorf("the user expression %q is not a string", name),
+      })
+    }
+  }
+
+  evaluator, ok := kernel.Adaptor.(AdaptorExpressionEvaluator)
+  if !ok {
+    for name := range expressions {
+      results[name] = NewUserExpression(ExpressionResult{
+        Err: fmt.Errorf("this kernel can not evaluate user expressions"),
+      })
+    }
+    return results
+  }
+
+  evaluated := evaluator.EvaluateUserExpressions(expressions)
+  for name := range expressions {
+    result, ok := evaluated[name]
+    if !ok {
+      result.Err = fmt.Errorf("the user expression %q was not evaluated", name)
+    }
+    results[name] = NewUserExpression(result)
+  }
+  return results
 }
 
-// handleShutdownRequest sends a "shutdown" message.
+// HandleHistoryRequest answers a history_request (in its `tail`, `range` 
+// or `search` mode) from the kernel's HistoryStore. 
+//
+func (kernel *IPyKernel) HandleHistoryRequest(receipt MsgReceipt) error {
+  // Extract the data from the request.
+  var request HistoryRequest
+  if err := receipt.Msg.ContentAs(&request); err != nil {
+    return receipt.Reply("history_reply", HistoryReply{
+      Status:       "error",
+      History:      []interface{}{},
+      ErrorContent: invalidContentError(err),
+    })
+  }
+
+  entries := []HistoryEntry{}
+  if kernel.History != nil {
+    switch request.HistAccessType {
+    case "tail":
+      entries = kernel.History.Tail(request.N)
+    case "range":
+      entries = kernel.History.Range(request.Session, request.Start, request.Stop)
+    case "search":
+      entries = kernel.History.Search(request.Pattern, request.N, request.Unique)
+    default:
+      log.Println("Unknown hist_access_type: ", request.HistAccessType)
+    }
+  }
+
+  // each history item is a (session, line, input) triple, or if output 
+  // was requested, a (session, line, (input, output)) triple 
+  //
+  history := make([]interface{}, 0, len(entries))
+  for _, entry := range entries {
+    if request.Output {
+      var output interface{}
+      if entry.Output != "" {
+        output = entry.Output
+      }
+      history = append(history, []interface{}{
+        entry.Session, entry.Line, []interface{}{ entry.Input, output },
+      })
+    } else {
+      history = append(history, []interface{}{
+        entry.Session, entry.Line, entry.Input,
+      })
+    }
+  }
+
+  return receipt.Reply("history_reply", HistoryReply{
+    Status:  "ok",
+    History: history,
+  })
+}
+
+// HandleInterruptRequest asks the adaptor, if it implements the 
+// AdaptorInterrupter interface, to interrupt the currently running 
+// evaluation and sends an interrupt_reply. An evaluation waiting for 
+// input (in MsgReceipt.ReadLine) is always interrupted. 
+//
+func (kernel *IPyKernel) HandleInterruptRequest(receipt MsgReceipt) error {
+  content := InterruptReply{ Status: "ok" }
+  kernel.interruptReadLines()
+
+  interrupter, ok := kernel.Adaptor.(AdaptorInterrupter)
+  if ok {
+    interrupter.Interrupt()
+  } else {
+    content.Status = "error"
+    content.ErrorContent = &ErrorContent{
+      EName:     "NotImplementedError",
+      EValue:    "this kernel can not be interrupted",
+      Traceback: []string{},
+    }
+  }
+
+  return receipt.Reply("interrupt_reply", content)
+}
+
+// handleShutdownRequest sends a "shutdown" message and asks Run to 
+// (gracefully) shutdown the kernel, or to restart the adaptor 
+// "in-process" if a restart was requested and the adaptor implements the 
+// AdaptorRestarter interface.
 //
 func (kernel *IPyKernel) HandleShutdownRequest(receipt MsgReceipt) {
-	content := receipt.Msg.Content.(map[string]interface{})
-	restart := content["restart"].(bool)
+	// a request which can not be decoded is taken as a (plain) shutdown
+	var request ShutdownRequest
+	if err := receipt.Msg.ContentAs(&request); err != nil {
+		log.Printf("Error decoding shutdown_request: %v\n", err)
+	}
+	restart := request.Restart
 
 	reply := ShutdownReply{
 		Restart: restart,
 	}
 
 	if err := receipt.Reply("shutdown_reply", reply); err != nil {
-		log.Fatal(err)
+		log.Printf("Error sending shutdown_reply: %v\n", err)
 	}
 
-	log.Println("Shutting down in response to shutdown_request")
-	os.Exit(0)
+	if _, canRestart := kernel.Adaptor.(AdaptorRestarter); restart && canRestart {
+		kernel.RequestRestart()
+		return
+	}
+	kernel.RequestShutdown()
 }
 
 // startHeartbeat starts a go-routine for handling heartbeat ping messages 
@@ -579,12 +1463,13 @@ func StartHeartbeat(hbSocket Socket, wg *sync.WaitGroup) (shutdown chan struct{}
 			case <-timeout.C:
 				continue
 			case v := <-msgs:
+				// A failed heartbeat socket stops the heartbeat (so that the 
+				// front-end, seeing the kernel as dead, can restart it).
+				if v.Err != nil {
+					log.Printf("Error reading heartbeat ping bytes: %v\n", v.Err)
+					return
+				}
 				hbSocket.RunWithSocket(func(echo zmq4.Socket) error {
-					if v.Err != nil {
-						log.Fatalf("Error reading heartbeat ping bytes: %v\n", v.Err)
-						return v.Err
-					}
-
 					// Send the received byte string back to let the front-end know that the kernel is alive.
 					if err := echo.Send(v.Msg); err != nil {
 						log.Printf("Error sending heartbeat pong bytes: %b\n", err)
@@ -0,0 +1,664 @@
+package goIPyKernel
+
+import(
+  "context"
+  "encoding/json"
+  "fmt"
+  "io/ioutil"
+  "net"
+  "os"
+  "path/filepath"
+  "testing"
+  "time"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+// testAdaptor is a minimal AdaptorImpl which records which of the
+// optional adaptor interfaces have been called.
+//
+type testAdaptor struct {
+  interrupted bool
+  shutdown    bool
+  restarted   chan struct{}
+  blocked     chan struct{}
+  release     chan struct{}
+  comms       *CommManager
+  receipt     MsgReceipt
+}
+
+func newTestAdaptor() *testAdaptor {
+  return &testAdaptor{
+    restarted: make(chan struct{}, 1),
+    blocked:   make(chan struct{}, 1),
+    release:   make(chan struct{}),
+  }
+}
+
+func (adaptor *testAdaptor) GetKernelInfo() KernelInfo {
+  return KernelInfo{
+    ProtocolVersion: ProtocolVersion,
+    Implementation:  "testAdaptor",
+  }
+}
+
+func (adaptor *testAdaptor) GetCodeWordCompletions(
+  code string,
+  cursorPos int,
+) (int, int, []string) {
+  return 0, 0, []string{}
+}
+
+func (adaptor *testAdaptor) SetupDisplayCallback(receipt MsgReceipt) {
+  adaptor.receipt = receipt
+}
+
+func (adaptor *testAdaptor) TeardownDisplayCallback() {
+  adaptor.receipt = MsgReceipt{}
+}
+
+func (adaptor *testAdaptor) EvaluateRemoveSpecialCommands(
+  outErr OutErr,
+  code string,
+) string {
+  return code
+}
+
+// EvaluateCode evaluates nothing, but fails when the code is "fail", 
+// asks the front-end to load the next input and exit when it is "exit", 
+// displays (and then updates) its progress when it is "progress", 
+// clears its output (once the next output arrives) when it is "clear", 
+// returns the error of reading a line (with ReadLine) when it is "input", 
+// blocks (after signalling blocked) until release is closed when it is 
+// "block" and then fails when it is "block and fail".
+//
+func (adaptor *testAdaptor) EvaluateCode(
+  execCount, execSubCount int,
+  code string,
+) (Data, error) {
+  switch code {
+  case "fail":
+    return Data{}, NewExecutionError("TestError", "the code failed", nil)
+  case "exit":
+    if err := adaptor.receipt.SetNextInput("next", false); err != nil {
+      return Data{}, err
+    }
+    if err := adaptor.receipt.AskExit(true); err != nil {
+      return Data{}, err
+    }
+  case "progress":
+    handle, err := adaptor.receipt.NewDisplayHandle()
+    if err != nil {
+      return Data{}, err
+    }
+    if err := handle.Display(Data{ Data: MIMEMap{ MIMETypeText: "0%" } }); err != nil {
+      return Data{}, err
+    }
+    if err := handle.Update(Data{ Data: MIMEMap{ MIMETypeText: "100%" } }); err != nil {
+      return Data{}, err
+    }
+  case "clear":
+    if err := adaptor.receipt.PublishClearOutput(true); err != nil {
+      return Data{}, err
+    }
+  case "input":
+    _, err := adaptor.receipt.ReadLine("name? ", false)
+    return Data{}, err
+  case "block", "block and fail":
+    adaptor.blocked <- struct{}{}
+    <-adaptor.release
+    if code == "block and fail" {
+      return Data{}, NewExecutionError("TestError", "the code failed", nil)
+    }
+  }
+  return Data{}, nil
+}
+
+// EvaluateUserExpressions echoes each expression as its value, except 
+// for "undefined" which raises a NameError.
+//
+func (adaptor *testAdaptor) EvaluateUserExpressions(
+  expressions map[string]string,
+) map[string]ExpressionResult {
+  results := make(map[string]ExpressionResult, len(expressions))
+  for name, expression := range expressions {
+    if expression == "undefined" {
+      results[name] = ExpressionResult{
+        Err: NewExecutionError("NameError", "undefined is not defined", nil),
+      }
+    } else {
+      results[name] = ExpressionResult{
+        Data: Data{ Data: MIMEMap{ MIMETypeText: expression } },
+      }
+    }
+  }
+  return results
+}
+
+func (adaptor *testAdaptor) Interrupt() { adaptor.interrupted = true }
+
+func (adaptor *testAdaptor) Shutdown() { adaptor.shutdown = true }
+
+func (adaptor *testAdaptor) Restart() error {
+  adaptor.restarted <- struct{}{}
+  return nil
+}
+
+// RegisterComms registers an "echo" comm target whose comms send back 
+// each message (and its buffers) they receive.
+//
+func (adaptor *testAdaptor) RegisterComms(comms *CommManager) {
+  adaptor.comms = comms
+  comms.RegisterTarget("echo",
+    func(comm *Comm, data map[string]interface{}, buffers [][]byte) {
+      comm.OnMsg(
+        func(comm *Comm, data map[string]interface{}, buffers [][]byte) {
+          comm.Send(data, buffers...)
+        },
+      )
+    },
+  )
+}
+
+// freePort returns a currently unused TCP port on the loopback interface.
+//
+func freePort(t *testing.T) int {
+  listener, err := net.Listen("tcp", "127.0.0.1:0")
+  assert.NoError(t, err, "could not find a free port")
+  defer listener.Close()
+  return listener.Addr().(*net.TCPAddr).Port
+}
+
+// writeConnectionFile writes a connection file (using free ports) into
+// a temporary directory.
+//
+func writeConnectionFile(t *testing.T) (string, ConnectionInfo) {
+  connInfo := ConnectionInfo{
+    SignatureScheme: "hmac-sha256",
+    Transport:       "tcp",
+    IP:              "127.0.0.1",
+    Key:             "a test key",
+    ShellPort:       freePort(t),
+    ControlPort:     freePort(t),
+    StdinPort:       freePort(t),
+    IOPubPort:       freePort(t),
+    HBPort:          freePort(t),
+  }
+  return writeConnectionInfo(t, connInfo), connInfo
+}
+
+// writeConnectionInfo writes the connInfo to a connection file in a
+// temporary directory.
+//
+func writeConnectionInfo(t *testing.T, connInfo ConnectionInfo) string {
+  connData, err := json.Marshal(connInfo)
+  assert.NoError(t, err, "could not marshal the connection info")
+
+  dir, err := ioutil.TempDir("", "goIPyKernel")
+  assert.NoError(t, err, "could not create a temporary directory")
+  t.Cleanup(func() { os.RemoveAll(dir) })
+
+  connFile := filepath.Join(dir, "connection_file.json")
+  err = ioutil.WriteFile(connFile, connData, 0600)
+  assert.NoError(t, err, "could not write the connection file")
+  return connFile
+}
+
+// send sends a message of msgType (with the content and any buffers) to 
+// the kernel over the socket.
+//
+func send(
+  t       *testing.T,
+  socket  zmq4.Socket,
+  signer  *MsgSigner,
+  msgType string,
+  content map[string]interface{},
+  buffers ...[]byte,
+) ComposedMsg {
+  msg, err := NewMsg(msgType, ComposedMsg{})
+  assert.NoError(t, err, "could not create the message")
+  msg.Content = content
+  msg.Buffers = buffers
+
+  msgParts, err := msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the message")
+  frames := append([][]byte{[]byte("<IDS|MSG>")}, msgParts...)
+  err = socket.Send(zmq4.NewMsgFrom(frames...))
+  assert.NoError(t, err, "could not send the message")
+  return msg
+}
+
+// request sends a request of msgType (with the content) to the kernel
+// over the socket and returns the kernel's reply.
+//
+func request(
+  t       *testing.T,
+  socket  zmq4.Socket,
+  signer  *MsgSigner,
+  msgType string,
+  content map[string]interface{},
+) ComposedMsg {
+  msg := send(t, socket, signer, msgType, content)
+
+  wireMsg, err := socket.Recv()
+  assert.NoError(t, err, "could not receive the reply")
+  reply, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+  assert.NoError(t, err, "could not decode the reply")
+  assert.Equal(t, msg.Header.MsgID, reply.ParentHeader.MsgID)
+  return reply
+}
+
+// runTestKernel runs the kernel (using the connFile) and returns a 
+// channel, closed once Run returns, together with a function which shuts 
+// the kernel down (if it is still running) and waits for Run to return.
+//
+func runTestKernel(
+  t        *testing.T,
+  kernel   *IPyKernel,
+  connFile string,
+) (<-chan struct{}, func()) {
+  stopped := make(chan struct{})
+  go func() {
+    defer close(stopped)
+    kernel.Run(connFile)
+  }()
+  return stopped, func() {
+    kernel.RequestShutdown()
+    waitForStop(t, stopped, "Run did not return after RequestShutdown")
+  }
+}
+
+// waitForStop waits (at most ten seconds) for the stopped channel to be 
+// closed, failing the test (with the message) if it is not.
+//
+func waitForStop(t *testing.T, stopped <-chan struct{}, message string) {
+  select {
+  case <-stopped:
+  case <-time.After(10 * time.Second):
+    t.Fatal(message)
+  }
+}
+
+// testFrontEnd is a (test) front-end connected to a kernel's shell, stdin 
+// and control sockets. As in Jupyter, its shell and stdin sockets share 
+// its identity.
+//
+type testFrontEnd struct {
+  connInfo ConnectionInfo
+  signer   *MsgSigner
+  shell    zmq4.Socket
+  stdin    zmq4.Socket
+  control  zmq4.Socket
+
+  // stopped is closed once the kernel's Run returns
+  stopped <-chan struct{}
+}
+
+// dialTestSocket returns a dealer socket dialed to the kernel's port.
+//
+func dialTestSocket(
+  t        *testing.T,
+  name     string,
+  port     int,
+  options  ...zmq4.Option,
+) zmq4.Socket {
+  socket := zmq4.NewDealer(context.Background(), options...)
+  err := socket.Dial(fmt.Sprintf("tcp://127.0.0.1:%d", port))
+  assert.NoError(t, err, "could not dial the %s socket", name)
+  return socket
+}
+
+// startTestKernel runs a kernel, with a testAdaptor and a temporary 
+// history, and connects a testFrontEnd to it. The returned function shuts 
+// the kernel down (if it is still running), waits for Run to return and 
+// closes the front-end's sockets.
+//
+func startTestKernel(t *testing.T) (*IPyKernel, testFrontEnd, func()) {
+  connFile, connInfo := writeConnectionFile(t)
+
+  history, err := OpenHistoryFile(tempHistoryPath(t))
+  assert.NoError(t, err, "could not open a new history file")
+
+  kernel := NewIPyKernel(newTestAdaptor())
+  kernel.History = history
+  stopped, stopKernel := runTestKernel(t, kernel, connFile)
+
+  identity := zmq4.WithID(zmq4.SocketIdentity("front-end"))
+  frontEnd := testFrontEnd{
+    connInfo: connInfo,
+    signer:   testSigner(t, connInfo.Key),
+    shell:    dialTestSocket(t, "shell", connInfo.ShellPort, identity),
+    stdin:    dialTestSocket(t, "stdin", connInfo.StdinPort, identity),
+    control:  dialTestSocket(t, "control", connInfo.ControlPort),
+    stopped:  stopped,
+  }
+  return kernel, frontEnd, func() {
+    stopKernel()
+    frontEnd.shell.Close()
+    frontEnd.stdin.Close()
+    frontEnd.control.Close()
+  }
+}
+
+func TestControlChannelRestartAndShutdown(t *testing.T) {
+  kernel, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  adaptor := kernel.Adaptor.(*testAdaptor)
+  control, signer := frontEnd.control, frontEnd.signer
+
+  // control messages are replied to on the control socket
+  reply := request(t, control, signer, "kernel_info_request", nil)
+  assert.Equal(t, "kernel_info_reply", reply.Header.MsgType)
+
+  reply = request(t, control, signer, "interrupt_request", nil)
+  assert.Equal(t, "interrupt_reply", reply.Header.MsgType)
+  assert.True(t, adaptor.interrupted, "the adaptor was not interrupted")
+
+  // a restart restarts the adaptor but keeps the kernel running...
+  kernel.ExecCounter = 42
+  reply = request(t, control, signer, "shutdown_request",
+    map[string]interface{}{ "restart": true },
+  )
+  assert.Equal(t, "shutdown_reply", reply.Header.MsgType)
+  select {
+  case <-adaptor.restarted:
+  case <-time.After(10 * time.Second):
+    t.Fatal("the adaptor was not restarted")
+  }
+  reply = request(t, control, signer, "kernel_info_request", nil)
+  assert.Equal(t, "kernel_info_reply", reply.Header.MsgType)
+  assert.False(t, adaptor.shutdown, "the adaptor was shutdown by a restart")
+
+  // ... while a shutdown stops it
+  reply = request(t, control, signer, "shutdown_request",
+    map[string]interface{}{ "restart": false },
+  )
+  assert.Equal(t, "shutdown_reply", reply.Header.MsgType)
+
+  waitForStop(t, frontEnd.stopped, "Run did not return after a shutdown_request")
+  assert.True(t, adaptor.shutdown, "the adaptor was not shutdown")
+  assert.Zero(t, kernel.ExecCounter, "the ExecCounter was not reset")
+}
+
+func TestControlRepliesWhileACellIsBlocked(t *testing.T) {
+  kernel, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  adaptor := kernel.Adaptor.(*testAdaptor)
+  shell, control, signer := frontEnd.shell, frontEnd.control, frontEnd.signer
+
+  execute := send(t, shell, signer, "execute_request",
+    map[string]interface{}{ "code": "block" },
+  )
+  select {
+  case <-adaptor.blocked:
+  case <-time.After(10 * time.Second):
+    t.Fatal("the cell was not evaluated")
+  }
+
+  // while the cell is blocked the control channel is still answered...
+  replies := make(chan ComposedMsg)
+  go func() {
+    defer close(replies)
+    replies <- request(t, control, signer, "kernel_info_request", nil)
+    replies <- request(t, control, signer, "interrupt_request", nil)
+  }()
+  for _, msgType := range []string{ "kernel_info_reply", "interrupt_reply" } {
+    select {
+    case reply := <-replies:
+      assert.Equal(t, msgType, reply.Header.MsgType)
+    case <-time.After(10 * time.Second):
+      close(adaptor.release)
+      t.Fatalf("no %s while the cell was blocked", msgType)
+    }
+  }
+  assert.True(t, adaptor.interrupted, "the adaptor was not interrupted")
+
+  // ... and the cell is only replied to once it is released
+  close(adaptor.release)
+  wireMsg, err := shell.Recv()
+  assert.NoError(t, err, "could not receive the execute_reply")
+  reply, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+  assert.NoError(t, err, "could not decode the execute_reply")
+  assert.Equal(t, "execute_reply", reply.Header.MsgType)
+  assert.Equal(t, execute.Header.MsgID, reply.ParentHeader.MsgID)
+}
+
+func TestReadLineIsInterruptedAndShutdown(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, stdin, control := frontEnd.shell, frontEnd.stdin, frontEnd.control
+  signer := frontEnd.signer
+
+  // (wait for the stdin socket to be connected)
+  request(t, shell, signer, "kernel_info_request", nil)
+  time.Sleep(200 * time.Millisecond)
+
+  // waitForInput sends an "input" execute_request and waits until the 
+  // kernel asks the front-end for input (which is never given). 
+  waitForInput := func() {
+    send(t, shell, signer, "execute_request", map[string]interface{}{
+      "code": "input", "allow_stdin": true, "stop_on_error": false,
+    })
+    wireMsg, err := stdin.Recv()
+    assert.NoError(t, err, "could not receive the input_request")
+    inputRequest, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+    assert.NoError(t, err, "could not decode the input_request")
+    assert.Equal(t, "input_request", inputRequest.Header.MsgType)
+  }
+
+  // an interrupt_request interrupts the waiting cell...
+  waitForInput()
+  reply := request(t, control, signer, "interrupt_request", nil)
+  assert.Equal(t, "interrupt_reply", reply.Header.MsgType)
+  wireMsg, err := shell.Recv()
+  assert.NoError(t, err, "could not receive the execute_reply")
+  reply, _, err = WireMsgToComposedMsg(wireMsg.Frames, signer)
+  assert.NoError(t, err, "could not decode the execute_reply")
+  content := reply.Content.(map[string]interface{})
+  assert.Equal(t, "error", content["status"])
+  assert.Equal(t, ErrInterrupted.Error(), content["evalue"])
+
+  // ... and a shutdown_request stops the kernel while a cell waits
+  waitForInput()
+  reply = request(t, control, signer, "shutdown_request",
+    map[string]interface{}{ "restart": false },
+  )
+  assert.Equal(t, "shutdown_reply", reply.Header.MsgType)
+  waitForStop(t, frontEnd.stopped,
+    "Run did not return while a cell was waiting for input",
+  )
+}
+
+func TestExecuteStoresHistory(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  execute := func(code string, silent, storeHistory bool) {
+    reply := request(t, shell, signer, "execute_request", map[string]interface{}{
+      "code":          code,
+      "silent":        silent,
+      "store_history": storeHistory,
+    })
+    assert.Equal(t, "execute_reply", reply.Header.MsgType)
+  }
+  execute("stored", false, true)
+  execute("not stored", false, false)
+  execute("silent", true, true)
+
+  reply := request(t, shell, signer, "history_request", map[string]interface{}{
+    "hist_access_type": "tail",
+    "n":                10,
+    "output":           true,
+  })
+  assert.Equal(t, "history_reply", reply.Header.MsgType)
+  content := reply.Content.(map[string]interface{})
+  assert.Equal(t, "ok", content["status"])
+  assert.Equal(t, []interface{}{
+    []interface{}{ float64(1), float64(1), []interface{}{ "stored", nil } },
+  }, content["history"])
+}
+
+func TestExecuteEvaluatesUserExpressions(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  reply := request(t, shell, signer, "execute_request", map[string]interface{}{
+    "code": "x := 42",
+    "user_expressions": map[string]interface{}{
+      "value":        "x",
+      "undefined":    "undefined",
+      "not a string": 42,
+    },
+  })
+  assert.Equal(t, "execute_reply", reply.Header.MsgType)
+  content := reply.Content.(map[string]interface{})
+  userExpressions := content["user_expressions"].(map[string]interface{})
+
+  assert.Equal(t, map[string]interface{}{
+    "status": "ok",
+    "data":   map[string]interface{}{ MIMETypeText: "x" },
+  }, userExpressions["value"])
+
+  undefined := userExpressions["undefined"].(map[string]interface{})
+  assert.Equal(t, "error", undefined["status"])
+  assert.Equal(t, "NameError", undefined["ename"])
+  assert.Equal(t, "undefined is not defined", undefined["evalue"])
+
+  notAString := userExpressions["not a string"].(map[string]interface{})
+  assert.Equal(t, "error", notAString["status"])
+}
+
+func TestStopOnErrorAbortsQueuedExecutions(t *testing.T) {
+  kernel, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  execute := func(stopOnError bool, codes ...string) {
+    for _, code := range codes {
+      send(t, shell, signer, "execute_request", map[string]interface{}{
+        "code":          code,
+        "stop_on_error": stopOnError,
+      })
+    }
+  }
+  replies := func(count int) []string {
+    statuses := []string{}
+    for i := 0; i < count; i++ {
+      wireMsg, err := shell.Recv()
+      assert.NoError(t, err, "could not receive the reply")
+      reply, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+      assert.NoError(t, err, "could not decode the reply")
+      content := reply.Content.(map[string]interface{})
+      statuses = append(statuses, content["status"].(string))
+    }
+    return statuses
+  }
+  adaptor := kernel.Adaptor.(*testAdaptor)
+
+  // queue ("Run All") several cells behind one which fails (once the 
+  // others have been received)...
+  execute(true, "block and fail")
+  <-adaptor.blocked
+  execute(true, "first", "second")
+  for kernel.shellMsgsReceived() < 3 {
+    time.Sleep(time.Millisecond)
+  }
+  close(adaptor.release)
+
+  // ... which aborts the cells queued behind it...
+  assert.Equal(t, []string{ "error", "aborted", "aborted" }, replies(3))
+  assert.Equal(t, 1, kernel.ExecCounter, "aborted cells were executed")
+
+  // ... but not those sent after its reply
+  execute(true, "third")
+  assert.Equal(t, []string{ "ok" }, replies(1))
+
+  // without stop_on_error the queued cells are executed
+  execute(false, "fail", "fourth")
+  assert.Equal(t, []string{ "error", "ok" }, replies(2))
+}
+
+func TestInvalidContentIsAnsweredWithAnError(t *testing.T) {
+  kernel, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  for msgType, content := range map[string]map[string]interface{}{
+    "execute_request":  { "code": 5 },
+    "complete_request": { "code": "fmt.", "cursor_pos": "end" },
+    "inspect_request":  { "code": []interface{}{} },
+    "history_request":  { "hist_access_type": "tail", "n": "ten" },
+  } {
+    reply := request(t, shell, signer, msgType, content)
+    replyContent := reply.Content.(map[string]interface{})
+    assert.Equal(t, "error", replyContent["status"], msgType)
+    assert.Equal(t, "InvalidContentError", replyContent["ename"], msgType)
+  }
+  assert.Equal(t, 0, kernel.ExecCounter, "an invalid cell was executed")
+
+  // the is_complete_reply has no "error" status
+  reply := request(t, shell, signer, "is_complete_request",
+    map[string]interface{}{ "code": true },
+  )
+  assert.Equal(t, "unknown", reply.Content.(map[string]interface{})["status"])
+}
+
+func TestBadContentDoesNotStopTheKernel(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  signer := frontEnd.signer
+
+  // well-framed (and signed) requests whose content is not a JSON object
+  for _, socket := range []zmq4.Socket{ frontEnd.shell, frontEnd.control } {
+    for _, msgType := range []string{
+      "execute_request", "history_request", "interrupt_request",
+    } {
+      for _, content := range []string{ "5", "[]", `"text"`, "{" } {
+        msg, err := NewMsg(msgType, ComposedMsg{})
+        assert.NoError(t, err, "could not create the message")
+        msgParts, err := msg.ToWireMsg(signer)
+        assert.NoError(t, err, "could not encode the message")
+        msgParts[4] = []byte(content)
+        msgParts[0] = signer.Sign(msgParts[1:5])
+        frames := append([][]byte{[]byte("<IDS|MSG>")}, msgParts...)
+        err = socket.Send(zmq4.NewMsgFrom(frames...))
+        assert.NoError(t, err, "could not send the message")
+      }
+    }
+  }
+
+  // the kernel is still running (and answering on both channels)
+  for _, socket := range []zmq4.Socket{ frontEnd.shell, frontEnd.control } {
+    reply := request(t, socket, signer, "kernel_info_request", nil)
+    assert.Equal(t, "kernel_info_reply", reply.Header.MsgType)
+  }
+  select {
+  case <-frontEnd.stopped:
+    t.Fatal("the kernel stopped")
+  default:
+  }
+}
+
+func TestMalformedMessagesAreSkipped(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  for _, frames := range [][][]byte{
+    { []byte("no delimiter") },
+    { []byte("<IDS|MSG>"), []byte("too short") },
+    { []byte("<IDS|MSG>"), []byte("bad signature"), {}, {}, {}, {} },
+  } {
+    err := shell.Send(zmq4.NewMsgFrom(frames...))
+    assert.NoError(t, err, "could not send a malformed message")
+  }
+
+  // the kernel is still running
+  reply := request(t, shell, signer, "kernel_info_request", nil)
+  assert.Equal(t, "kernel_info_reply", reply.Header.MsgType)
+}
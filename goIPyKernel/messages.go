@@ -1,10 +1,9 @@
 package goIPyKernel
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"time"
 
@@ -16,7 +15,11 @@ import (
 
 const (
 	// ProtocolVersion defines the Jupyter protocol version.
-	ProtocolVersion string = "5.0"
+	ProtocolVersion string = "5.3"
+
+	// TimestampFormat is the (ISO 8601, with microseconds) format of the date in a
+	// message header.
+	TimestampFormat string = "2006-01-02T15:04:05.000000Z07:00"
 )
 
 
@@ -36,6 +39,10 @@ type ComposedMsg struct {
 	ParentHeader MsgHeader
 	Metadata     map[string]interface{}
 	Content      interface{}
+
+	// Buffers holds any (binary) buffers sent after the content. As the 
+	// protocol specifies, buffers are not included in the signature. 
+	Buffers [][]byte
 }
 
 // msgReceipt represents a received message, its return identities, and
@@ -44,6 +51,40 @@ type MsgReceipt struct {
 	Msg        ComposedMsg
 	Identities [][]byte
 	Sockets    SocketGroup
+
+  // ReplySocket is the socket (either the shell or the control socket) on 
+  // which the message was received and hence on which Reply replies. If 
+  // not set, Reply uses the shell socket. 
+  //
+  ReplySocket Socket
+
+  // AllowStdin is true if the front-end which sent an execute_request is 
+  // able to answer input_requests (see the `allow_stdin` flag). 
+  //
+  AllowStdin bool
+
+  // InputReplies delivers the input_reply messages received on the stdin 
+  // socket to (the ReadLine method of) this receipt. 
+  //
+  InputReplies <-chan ComposedMsg
+
+  // Interrupted is closed when the kernel is interrupted (and 
+  // ShuttingDown when it is shutdown), so that ReadLine stops waiting for 
+  // an input_reply which may never arrive. 
+  //
+  Interrupted  <-chan struct{}
+  ShuttingDown <-chan struct{}
+
+  // Payloads collects the payloads (see SetNextInput, Page and AskExit) 
+  // for the execute_reply of an execute_request (and is nil for any 
+  // other message). 
+  //
+  Payloads *Payloads
+
+  // received is the number of a message received on the shell socket 
+  // (see IPyKernel.shellReceived), or zero.
+  //
+  received uint64
 }
 
 
@@ -55,44 +96,121 @@ func (e *InvalidSignatureError) Error() string {
 	return "A message had an invalid signature"
 }
 
+// MissingDelimiterError is returned when a received message has no "<IDS|MSG>"
+// delimiter frame.
+type MissingDelimiterError struct{}
+
+func (e *MissingDelimiterError) Error() string {
+	return "A message had no <IDS|MSG> delimiter"
+}
+
+// ShortMessageError is returned when a received message has fewer than the five
+// frames (signature, header, parent header, metadata and content) which must follow
+// the delimiter.
+type ShortMessageError struct {
+	Frames int
+}
+
+func (e *ShortMessageError) Error() string {
+	return fmt.Sprintf("A message had only %d of the 5 frames required after <IDS|MSG>", e.Frames)
+}
+
+// InvalidJSONError is returned when a frame (the header, parent header, metadata or
+// content) of a received message is not a valid JSON object.
+type InvalidJSONError struct {
+	Frame string
+	Err   error
+}
+
+func (e *InvalidJSONError) Error() string {
+	return fmt.Sprintf("A message had an invalid %s: %v", e.Frame, e.Err)
+}
+
+func (e *InvalidJSONError) Unwrap() error {
+	return e.Err
+}
+
 // WireMsgToComposedMsg translates a multipart ZMQ messages received from a socket into
 // a ComposedMsg struct and a slice of return identities. This includes verifying the
-// message signature.
-func WireMsgToComposedMsg(msgparts [][]byte, signkey []byte) (ComposedMsg, [][]byte, error) {
+// message signature (with the signer's scheme and key).
+//
+// The content is decoded into a map[string]interface{} (see WireMsgToTypedMsg).
+//
+// Malformed messages are reported by a MissingDelimiterError, ShortMessageError,
+// InvalidSignatureError or InvalidJSONError.
+func WireMsgToComposedMsg(msgparts [][]byte, signer *MsgSigner) (ComposedMsg, [][]byte, error) {
+	return wireMsgToComposedMsg(msgparts, signer, false)
+}
+
+// WireMsgToTypedMsg translates a multipart ZMQ message, as WireMsgToComposedMsg does,
+// but decodes the content into a pointer to its msg_type's content struct (for
+// example an *ExecuteRequest) with the protocol's defaults for any missing fields.
+// The contents of unknown msg_types are decoded into a map[string]interface{}.
+func WireMsgToTypedMsg(msgparts [][]byte, signer *MsgSigner) (ComposedMsg, [][]byte, error) {
+	return wireMsgToComposedMsg(msgparts, signer, true)
+}
+
+// wireMsgToComposedMsg translates a multipart ZMQ message (decoding the content into
+// its typed struct if typed is true).
+func wireMsgToComposedMsg(msgparts [][]byte, signer *MsgSigner, typed bool) (ComposedMsg, [][]byte, error) {
+	var msg ComposedMsg
 
 	i := 0
-	for string(msgparts[i]) != "<IDS|MSG>" {
+	for i < len(msgparts) && string(msgparts[i]) != "<IDS|MSG>" {
 		i++
 	}
+	if i == len(msgparts) {
+		return msg, nil, &MissingDelimiterError{}
+	}
+	if frames := len(msgparts) - (i + 1); frames < 5 {
+		return msg, nil, &ShortMessageError{Frames: frames}
+	}
 	identities := msgparts[:i]
 
 	// Validate signature.
-	var msg ComposedMsg
-	if len(signkey) != 0 {
-		mac := hmac.New(sha256.New, signkey)
-		for _, msgpart := range msgparts[i+2 : i+6] {
-			mac.Write(msgpart)
+	if !signer.Verify(msgparts[i+1], msgparts[i+2:i+6]) {
+		return msg, nil, &InvalidSignatureError{}
+	}
+
+	// Unmarshal contents (the content must be a JSON object).
+	var content map[string]interface{}
+	for _, frame := range []struct {
+		name  string
+		part  []byte
+		value interface{}
+	}{
+		{"header", msgparts[i+2], &msg.Header},
+		{"parent header", msgparts[i+3], &msg.ParentHeader},
+		{"metadata", msgparts[i+4], &msg.Metadata},
+		{"content", msgparts[i+5], &content},
+	} {
+		if err := json.Unmarshal(frame.part, frame.value); err != nil {
+			return msg, nil, &InvalidJSONError{Frame: frame.name, Err: err}
 		}
-		signature := make([]byte, hex.DecodedLen(len(msgparts[i+1])))
-		hex.Decode(signature, msgparts[i+1])
-		if !hmac.Equal(mac.Sum(nil), signature) {
-			return msg, nil, &InvalidSignatureError{}
+	}
+	msg.Content = content
+
+	if typed {
+		typedContent, err := DecodeContent(msg.Header.MsgType, msgparts[i+5])
+		if err != nil {
+			return msg, nil, &InvalidJSONError{Frame: "content", Err: err}
 		}
+		msg.Content = typedContent
 	}
 
-	// Unmarshal contents.
-	json.Unmarshal(msgparts[i+2], &msg.Header)
-	json.Unmarshal(msgparts[i+3], &msg.ParentHeader)
-	json.Unmarshal(msgparts[i+4], &msg.Metadata)
-	json.Unmarshal(msgparts[i+5], &msg.Content)
+	// Any remaining frames are (binary) buffers.
+	if len(msgparts) > i+6 {
+		msg.Buffers = msgparts[i+6:]
+	}
 	return msg, identities, nil
 }
 
 // ToWireMsg translates a ComposedMsg into a multipart ZMQ message ready to send, and
-// signs it. This does not add the return identities or the delimiter.
-func (msg ComposedMsg) ToWireMsg(signkey []byte) ([][]byte, error) {
+// signs it (with the signer's scheme and key). This does not add the return identities
+// or the delimiter. Any buffers follow the (signed) content.
+func (msg ComposedMsg) ToWireMsg(signer *MsgSigner) ([][]byte, error) {
 
-	msgparts := make([][]byte, 5)
+	msgparts := make([][]byte, 5, 5+len(msg.Buffers))
 
 	header, err := json.Marshal(msg.Header)
 	if err != nil {
@@ -123,32 +241,30 @@ func (msg ComposedMsg) ToWireMsg(signkey []byte) ([][]byte, error) {
 	msgparts[4] = content
 
 	// Sign the message.
-	if len(signkey) != 0 {
-		mac := hmac.New(sha256.New, signkey)
-		for _, msgpart := range msgparts[1:] {
-			mac.Write(msgpart)
-		}
-		msgparts[0] = make([]byte, hex.EncodedLen(mac.Size()))
-		hex.Encode(msgparts[0], mac.Sum(nil))
-	}
+	msgparts[0] = signer.Sign(msgparts[1:5])
 
-	return msgparts, nil
+	return append(msgparts, msg.Buffers...), nil
 }
 
 // SendResponse sends a message back to return identities of the received message.
 func (receipt *MsgReceipt) SendResponse(socket zmq4.Socket, msg ComposedMsg) error {
+	return receipt.sendWithIdentities(socket, receipt.Identities, msg)
+}
 
-	msgParts, err := msg.ToWireMsg(receipt.Sockets.Key)
+// sendWithIdentities sends a message with the identities (or, on IOPub, the topic)
+// as its leading frames.
+func (receipt *MsgReceipt) sendWithIdentities(
+	socket zmq4.Socket,
+	identities [][]byte,
+	msg ComposedMsg,
+) error {
+
+	wireMsg, err := receipt.toWireMsgWithIdentities(identities, msg)
 	if err != nil {
 		return err
 	}
 
-	var frames = make([][]byte, 0, len(receipt.Identities)+1+len(msgParts))
-	frames = append(frames, receipt.Identities...)
-	frames = append(frames, []byte("<IDS|MSG>"))
-	frames = append(frames, msgParts...)
-
-	err = socket.SendMulti(zmq4.NewMsgFrom(frames...))
+	err = socket.SendMulti(wireMsg)
 	if err != nil {
 		return err
 	}
@@ -156,8 +272,29 @@ func (receipt *MsgReceipt) SendResponse(socket zmq4.Socket, msg ComposedMsg) err
 	return nil
 }
 
+// toWireMsgWithIdentities encodes (and signs) a message with the identities (or, on
+// IOPub, the topic) as its leading frames.
+func (receipt *MsgReceipt) toWireMsgWithIdentities(
+	identities [][]byte,
+	msg ComposedMsg,
+) (zmq4.Msg, error) {
+
+	msgParts, err := msg.ToWireMsg(receipt.Sockets.Signer)
+	if err != nil {
+		return zmq4.Msg{}, err
+	}
+
+	var frames = make([][]byte, 0, len(identities)+1+len(msgParts))
+	frames = append(frames, identities...)
+	frames = append(frames, []byte("<IDS|MSG>"))
+	frames = append(frames, msgParts...)
+
+	return zmq4.NewMsgFrom(frames...), nil
+}
+
 // NewMsg creates a new ComposedMsg to respond to a parent message.
-// This includes setting up its headers.
+// This includes setting up its headers (whose version is the protocol version
+// negotiated with the parent's sender).
 func NewMsg(msgType string, parent ComposedMsg) (ComposedMsg, error) {
 	var msg ComposedMsg
 
@@ -165,8 +302,8 @@ func NewMsg(msgType string, parent ComposedMsg) (ComposedMsg, error) {
 	msg.Header.Session = parent.Header.Session
 	msg.Header.Username = parent.Header.Username
 	msg.Header.MsgType = msgType
-	msg.Header.ProtocolVersion = ProtocolVersion
-	msg.Header.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	msg.Header.ProtocolVersion = NegotiateVersion(parent.Header.ProtocolVersion)
+	msg.Header.Timestamp = time.Now().UTC().Format(TimestampFormat)
 
 	u, err := uuid.NewV4()
 	if err != nil {
@@ -177,9 +314,21 @@ func NewMsg(msgType string, parent ComposedMsg) (ComposedMsg, error) {
 	return msg, nil
 }
 
-// Publish creates a new ComposedMsg and sends it back to the return identities over the
-// IOPub channel.
-func (receipt *MsgReceipt) Publish(msgType string, content interface{}) error {
+// Publish creates a new ComposedMsg (with any buffers) and publishes it over the IOPub
+// channel (with the message's IOPubTopic).
+func (receipt *MsgReceipt) Publish(msgType string, content interface{}, buffers ...[]byte) error {
+	topic := IOPubTopic(receipt.Sockets.KernelID, msgType, content)
+	return receipt.publishWithTopic(topic, msgType, content, buffers...)
+}
+
+// publishWithTopic creates a new ComposedMsg (with any buffers) and publishes it over
+// the IOPub channel with the topic.
+func (receipt *MsgReceipt) publishWithTopic(
+	topic string,
+	msgType string,
+	content interface{},
+	buffers ...[]byte,
+) error {
 	msg, err := NewMsg(msgType, receipt.Msg)
 
 	if err != nil {
@@ -187,50 +336,46 @@ func (receipt *MsgReceipt) Publish(msgType string, content interface{}) error {
 	}
 
 	msg.Content = content
+	msg.Buffers = buffers
 	return receipt.Sockets.IOPubSocket.RunWithSocket(func(iopub zmq4.Socket) error {
-		return receipt.SendResponse(iopub, msg)
+		return receipt.sendWithIdentities(iopub, [][]byte{[]byte(topic)}, msg)
 	})
 }
 
-// Reply creates a new ComposedMsg and sends it back to the return identities over the
-// Shell channel.
-func (receipt *MsgReceipt) Reply(msgType string, content interface{}) error {
+// Reply creates a new ComposedMsg (with any buffers) and sends it back to the return
+// identities over the channel (Shell or Control) on which the message was received.
+func (receipt *MsgReceipt) Reply(msgType string, content interface{}, buffers ...[]byte) error {
 	msg, err := NewMsg(msgType, receipt.Msg)
 
 	if err != nil {
 		return err
 	}
 
+	replySocket := receipt.ReplySocket
+	if replySocket.Socket == nil {
+		replySocket = receipt.Sockets.ShellSocket
+	}
+
 	msg.Content = content
-	return receipt.Sockets.ShellSocket.RunWithSocket(func(shell zmq4.Socket) error {
-		return receipt.SendResponse(shell, msg)
+	msg.Buffers = buffers
+	return replySocket.RunWithSocket(func(socket zmq4.Socket) error {
+		return receipt.SendResponse(socket, msg)
 	})
 }
 
 // PublishKernelStatus publishes a status message notifying front-ends of the state the kernel is in. Supports
 // states "starting", "busy", and "idle".
 func (receipt *MsgReceipt) PublishKernelStatus(status string) error {
-	return receipt.Publish("status",
-		struct {
-			ExecutionState string `json:"execution_state"`
-		}{
-			ExecutionState: status,
-		},
-	)
+	return receipt.Publish("status", Status{ExecutionState: status})
 }
 
 // PublishExecutionInput publishes a status message notifying front-ends of what code is
 // currently being executed.
 func (receipt *MsgReceipt) PublishExecutionInput(execCount int, code string) error {
-	return receipt.Publish("execute_input",
-		struct {
-			ExecCount int    `json:"execution_count"`
-			Code      string `json:"code"`
-		}{
-			ExecCount: execCount,
-			Code:      code,
-		},
-	)
+	return receipt.Publish("execute_input", ExecuteInput{
+		Code:           code,
+		ExecutionCount: execCount,
+	})
 }
 
 func EnsureMIMEMap(bundle MIMEMap) MIMEMap {
@@ -255,46 +400,56 @@ func MergeMIMEMap(a MIMEMap, b MIMEMap) MIMEMap {
 
 // PublishExecuteResult publishes the result of the `execCount` execution as a string.
 func (receipt *MsgReceipt) PublishExecutionResult(execCount int, data Data) error {
-	return receipt.Publish("execute_result", struct {
-		ExecCount int     `json:"execution_count"`
-		Data      MIMEMap `json:"data"`
-		Metadata  MIMEMap `json:"metadata"`
-	}{
-		ExecCount: execCount,
-		Data:      data.Data,
-		Metadata:  EnsureMIMEMap(data.Metadata),
+	return receipt.Publish("execute_result", ExecuteResult{
+		ExecutionCount: execCount,
+		Data:           data.Data,
+		Metadata:       EnsureMIMEMap(data.Metadata),
 	})
 }
 
-// PublishExecuteResult publishes a serialized error that was encountered during execution.
-func (receipt *MsgReceipt) PublishExecutionError(err string, trace []string) error {
-	return receipt.Publish("error",
-		struct {
-			Name  string   `json:"ename"`
-			Value string   `json:"evalue"`
-			Trace []string `json:"traceback"`
-		}{
-			Name:  "ERROR",
-			Value: err,
-			Trace: trace,
-		},
-	)
+// PublishExecutionError publishes a serialized error that was encountered during execution.
+func (receipt *MsgReceipt) PublishExecutionError(ename, evalue string, trace []string) error {
+	return receipt.Publish("error", Error{
+		EName:     ename,
+		EValue:    evalue,
+		Traceback: trace,
+	})
 }
 
 // PublishDisplayData publishes a single image.
 func (receipt *MsgReceipt) PublishDisplayData(data Data) error {
 	// copy Data in a struct with appropriate json tags
-	return receipt.Publish("display_data", struct {
-		Data      MIMEMap `json:"data"`
-		Metadata  MIMEMap `json:"metadata"`
-		Transient MIMEMap `json:"transient"`
-	}{
+	return receipt.Publish("display_data", DisplayData{
 		Data:      data.Data,
 		Metadata:  EnsureMIMEMap(data.Metadata),
 		Transient: EnsureMIMEMap(data.Transient),
 	})
 }
 
+// PublishUpdateDisplayData publishes an update_display_data which replaces 
+// (in place) every output displayed with the data's display id (see 
+// WithDisplayID). 
+//
+func (receipt *MsgReceipt) PublishUpdateDisplayData(data Data) error {
+  if DisplayID(data) == "" {
+    return ErrNoDisplayID
+  }
+  return receipt.Publish("update_display_data", DisplayData{
+    Data:      data.Data,
+    Metadata:  EnsureMIMEMap(data.Metadata),
+    Transient: data.Transient,
+  })
+}
+
+// PublishClearOutput publishes a clear_output which clears the outputs of
+// the current cell, for example between the frames of an animation. If
+// wait is true, the outputs are only cleared once the next output
+// arrives (which avoids flickering).
+//
+func (receipt *MsgReceipt) PublishClearOutput(wait bool) error {
+  return receipt.Publish("clear_output", ClearOutput{ Wait: wait })
+}
+
 const (
 	// StreamStdout defines the stream name for standard out on the front-end. It
 	// is used in `PublishWriteStream` to specify the stream to write to.
@@ -308,15 +463,10 @@ const (
 // PublishWriteStream prints the data string to a stream on the front-end. This is
 // either `StreamStdout` or `StreamStderr`.
 func (receipt *MsgReceipt) PublishWriteStream(stream string, data string) error {
-	return receipt.Publish("stream",
-		struct {
-			Stream string `json:"name"`
-			Data   string `json:"text"`
-		}{
-			Stream: stream,
-			Data:   data,
-		},
-	)
+	return receipt.Publish("stream", Stream{
+		Name: stream,
+		Text: data,
+	})
 }
 
 // JupyterStreamWriter is an `io.Writer` implementation that writes the data to the notebook
@@ -338,7 +488,114 @@ func (writer *JupyterStreamWriter) Write(p []byte) (int, error) {
 	return n, nil
 }
 
+// ErrStdinNotAllowed is returned by ReadLine when the front-end has not 
+// allowed the kernel to request input (see the `allow_stdin` flag of the 
+// execute_request). 
+//
+var ErrStdinNotAllowed = errors.New("stdin is not supported by this front-end")
+
+// ErrInterrupted is returned by ReadLine when the kernel is interrupted 
+// while waiting for an input_reply. 
+//
+var ErrInterrupted = errors.New("interrupted while waiting for input")
+
+// ErrShutdown is returned by ReadLine when the kernel is shutdown while 
+// waiting for an input_reply. 
+//
+var ErrShutdown = errors.New("shutdown while waiting for input")
+
+// ReadLine sends an input_request (with the given prompt) to the 
+// front-end over the Stdin channel and waits for the corresponding 
+// input_reply. When password is true the front-end should not echo the 
+// user's input. If the kernel is interrupted or shutdown before the 
+// input_reply arrives, ReadLine returns ErrInterrupted or ErrShutdown. 
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#messages-on-the-stdin-router-dealer-channel
+//
+func (receipt *MsgReceipt) ReadLine(prompt string, password bool) (string, error) {
+  if !receipt.AllowStdin || receipt.InputReplies == nil {
+    return "", ErrStdinNotAllowed
+  }
+
+  request, err := NewMsg("input_request", receipt.Msg)
+  if err != nil {
+    return "", err
+  }
+  request.Content = InputRequest{
+    Prompt:   prompt,
+    Password: password,
+  }
+
+  err = receipt.Sockets.StdinSocket.RunWithSocket(func(stdin zmq4.Socket) error {
+    return receipt.SendResponse(stdin, request)
+  })
+  if err != nil {
+    return "", err
+  }
+
+  // wait for the reply to THIS request (ignoring any stale replies to 
+  // earlier requests) 
+  //
+  for {
+    select {
+    case reply, ok := <-receipt.InputReplies:
+      if !ok {
+        return "", io.EOF
+      }
+      if reply.ParentHeader.MsgID != request.Header.MsgID {
+        continue
+      }
+      var content InputReply
+      if err := reply.ContentAs(&content); err != nil {
+        return "", err
+      }
+      return content.Value, nil
+    case <-receipt.Interrupted:
+      return "", ErrInterrupted
+    case <-receipt.ShuttingDown:
+      return "", ErrShutdown
+    }
+  }
+}
+
+// JupyterStdinReader is an `io.Reader` implementation that reads lines of 
+// input from the notebook front-end. 
+type JupyterStdinReader struct {
+	receipt *MsgReceipt
+	buffer  []byte
+}
+
+// NewJupyterStdinReader creates a JupyterStdinReader which requests its 
+// input (using `ReadLine`) from the front-end which sent the receipt's 
+// message. 
+//
+func NewJupyterStdinReader(receipt *MsgReceipt) *JupyterStdinReader {
+  return &JupyterStdinReader{receipt: receipt}
+}
+
+// Read implements `io.Reader.Read` by requesting a new line of input, via 
+// `ReadLine`, whenever the previous line has been consumed. A front-end 
+// which does not allow stdin is treated as an empty input (`io.EOF`). 
+//
+func (reader *JupyterStdinReader) Read(p []byte) (int, error) {
+  if len(reader.buffer) == 0 {
+    line, err := reader.receipt.ReadLine("", false)
+    if err == ErrStdinNotAllowed {
+      return 0, io.EOF
+    }
+    if err != nil {
+      return 0, err
+    }
+    reader.buffer = []byte(line + "\n")
+  }
+
+  n := copy(p, reader.buffer)
+  reader.buffer = reader.buffer[n:]
+  return n, nil
+}
+
 type OutErr struct {
 	Out io.Writer
 	Err io.Writer
+	In  io.Reader
 }
@@ -0,0 +1,87 @@
+//go:build go1.18
+// +build go1.18
+
+package goIPyKernel
+
+import(
+  "bytes"
+  "testing"
+)
+
+// Fuzzing needs go 1.18 (or later), run with (for example):
+//
+//   go test -fuzz FuzzWireMsgToComposedMsg -fuzztime 30s
+//
+
+// fuzzFrameSeparator separates the frames of a fuzzed multipart message.
+//
+var fuzzFrameSeparator = []byte("\x00|\x00")
+
+// fuzzSeed encodes (unsigned) a message of msgType with the content as a
+// fuzzing seed.
+//
+func fuzzSeed(f *testing.F, msgType string, content interface{}, buffers ...[]byte) []byte {
+  msg, err := NewMsg(msgType, ComposedMsg{})
+  if err != nil {
+    f.Fatal(err)
+  }
+  msg.Content = content
+  msg.Buffers = buffers
+  msgParts, err := msg.ToWireMsg(nil)
+  if err != nil {
+    f.Fatal(err)
+  }
+  frames := append([][]byte{ []byte("front-end"), []byte("<IDS|MSG>") }, msgParts...)
+  return bytes.Join(frames, fuzzFrameSeparator)
+}
+
+func FuzzWireMsgToComposedMsg(f *testing.F) {
+  f.Add(fuzzSeed(f, "kernel_info_request", nil))
+  f.Add(fuzzSeed(f, "execute_request", map[string]interface{}{
+    "code":   "fmt.Println(\"Hello\")",
+    "silent": false,
+  }))
+  f.Add(fuzzSeed(f, "comm_msg", map[string]interface{}{ "comm_id": "a-comm" },
+    []byte("\x00\x01\x02"), []byte{},
+  ))
+  f.Add([]byte("<IDS|MSG>"))
+  f.Add([]byte{})
+
+  f.Fuzz(func(t *testing.T, wireMsg []byte) {
+    frames := bytes.Split(wireMsg, fuzzFrameSeparator)
+
+    // an unsigned message is checked as far as its JSON frames...
+    msg, ids, err := WireMsgToComposedMsg(frames, nil)
+    if err != nil {
+      return
+    }
+    if len(ids) >= len(frames) {
+      t.Fatalf("%d identities in a message of %d frames", len(ids), len(frames))
+    }
+
+    // ... and any message which is decoded can be encoded again
+    signer := &MsgSigner{ Key: []byte("a key") }
+    msgParts, err := msg.ToWireMsg(signer)
+    if err != nil {
+      t.Fatalf("could not encode a decoded message: %v", err)
+    }
+    resigned := append([][]byte{ []byte("<IDS|MSG>") }, msgParts...)
+    if _, _, err := WireMsgToComposedMsg(resigned, signer); err != nil {
+      t.Fatalf("could not decode a re-encoded message: %v", err)
+    }
+  })
+}
+
+func FuzzWireMsgSignature(f *testing.F) {
+  f.Add("hmac-sha256", []byte("a key"), fuzzSeed(f, "kernel_info_request", nil))
+  f.Add("hmac-md5", []byte{}, []byte("<IDS|MSG>"))
+
+  f.Fuzz(func(t *testing.T, scheme string, key []byte, wireMsg []byte) {
+    signer, err := NewMsgSigner(scheme, key)
+    if err != nil {
+      return
+    }
+    // messages must never panic, whatever their signatures
+    WireMsgToComposedMsg(bytes.Split(wireMsg, fuzzFrameSeparator), signer)
+  })
+}
@@ -0,0 +1,259 @@
+package goIPyKernel
+
+import(
+  "context"
+  "crypto/rand"
+  "errors"
+  "io"
+  "sync"
+  "testing"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestReadLineNotAllowed(t *testing.T) {
+  receipt := MsgReceipt{ InputReplies: make(chan ComposedMsg) }
+
+  _, err := receipt.ReadLine("prompt: ", false)
+  assert.Equal(t, ErrStdinNotAllowed, err, "stdin should not be allowed")
+
+  buffer := make([]byte, 10)
+  n, err := NewJupyterStdinReader(&receipt).Read(buffer)
+  assert.Zero(t, n, "nothing should have been read")
+  assert.Equal(t, io.EOF, err, "reader should be at EOF")
+}
+
+func TestReadLine(t *testing.T) {
+  ctx := context.Background()
+  signer := testSigner(t, "a test key")
+
+  stdin := zmq4.NewRouter(ctx)
+  defer stdin.Close()
+  err := stdin.Listen("tcp://127.0.0.1:0")
+  assert.NoError(t, err, "could not listen on the stdin socket")
+
+  frontEnd := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("front-end")))
+  defer frontEnd.Close()
+  err = frontEnd.Dial("tcp://" + stdin.Addr().String())
+  assert.NoError(t, err, "could not dial the stdin socket")
+
+  // ensure the router knows about the front-end before we send to it
+  err = frontEnd.Send(zmq4.NewMsgString("hello"))
+  assert.NoError(t, err, "could not send hello")
+  hello, err := stdin.Recv()
+  assert.NoError(t, err, "could not receive hello")
+
+  inputReplies := make(chan ComposedMsg, 1)
+  receipt := MsgReceipt{
+    Identities:   hello.Frames[:1],
+    Sockets:      SocketGroup{
+      StdinSocket: Socket{ Socket: stdin, Lock: &sync.Mutex{} },
+      Signer:      signer,
+    },
+    AllowStdin:   true,
+    InputReplies: inputReplies,
+  }
+
+  type readResult struct {
+    line string
+    err  error
+  }
+  results := make(chan readResult)
+  go func() {
+    line, err := receipt.ReadLine("name? ", true)
+    results <- readResult{line, err}
+  }()
+
+  // the front-end should receive an input_request...
+  wireMsg, err := frontEnd.Recv()
+  assert.NoError(t, err, "could not receive the input_request")
+  request, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+  assert.NoError(t, err, "could not decode the input_request")
+  assert.Equal(t, "input_request", request.Header.MsgType)
+  content := request.Content.(map[string]interface{})
+  assert.Equal(t, "name? ", content["prompt"])
+  assert.Equal(t, true, content["password"])
+
+  // ... stale replies should be ignored ...
+  stale := ComposedMsg{ Content: map[string]interface{}{ "value": "stale" } }
+  stale.ParentHeader.MsgID = "not-the-request"
+  inputReplies <- stale
+
+  // ... and the answering input_reply returned
+  reply, err := NewMsg("input_reply", request)
+  assert.NoError(t, err, "could not create the input_reply")
+  reply.Content = map[string]interface{}{ "value": "gopher" }
+  inputReplies <- reply
+
+  result := <-results
+  assert.NoError(t, result.err, "ReadLine failed")
+  assert.Equal(t, "gopher", result.line)
+}
+
+func TestBuffersRoundTrip(t *testing.T) {
+  signer := testSigner(t, "a test key")
+
+  large := make([]byte, 8*1024*1024)
+  _, err := rand.Read(large)
+  assert.NoError(t, err, "could not create a large buffer")
+
+  msg, err := NewMsg("comm_msg", ComposedMsg{})
+  assert.NoError(t, err, "could not create the message")
+  msg.Content = map[string]interface{}{ "comm_id": "a-comm" }
+  msg.Buffers = [][]byte{ large, {}, []byte("\x00\xff") }
+
+  msgParts, err := msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the message")
+  assert.Len(t, msgParts, 5+3, "buffers should follow the content")
+
+  identities := [][]byte{ []byte("front-end") }
+  frames := append(append(identities, []byte("<IDS|MSG>")), msgParts...)
+  received, ids, err := WireMsgToComposedMsg(frames, signer)
+  assert.NoError(t, err, "could not decode the message")
+  assert.Equal(t, identities, ids)
+  assert.Equal(t, msg.Header, received.Header)
+  assert.Equal(t, msg.Content, received.Content)
+  assert.Equal(t, msg.Buffers, received.Buffers)
+
+  // messages without buffers have none
+  msg.Buffers = nil
+  msgParts, err = msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the message")
+  frames = append([][]byte{ []byte("<IDS|MSG>") }, msgParts...)
+  received, _, err = WireMsgToComposedMsg(frames, signer)
+  assert.NoError(t, err, "could not decode the message")
+  assert.Nil(t, received.Buffers)
+}
+
+func TestBuffersAreNotSigned(t *testing.T) {
+  signer := testSigner(t, "a test key")
+
+  msg, err := NewMsg("comm_msg", ComposedMsg{})
+  assert.NoError(t, err, "could not create the message")
+  msg.Buffers = [][]byte{ []byte("a buffer") }
+
+  withBuffer, err := msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the message")
+  msg.Buffers = nil
+  withoutBuffer, err := msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the message")
+  assert.Equal(t, withoutBuffer[0], withBuffer[0],
+    "the signature should not depend upon the buffers",
+  )
+
+  // but the signed frames are still verified
+  withBuffer[4] = []byte(`{"tampered": true}`)
+  frames := append([][]byte{ []byte("<IDS|MSG>") }, withBuffer...)
+  _, _, err = WireMsgToComposedMsg(frames, signer)
+  assert.IsType(t, &InvalidSignatureError{}, err)
+}
+
+func TestReplyWithBuffers(t *testing.T) {
+  ctx := context.Background()
+  signer := testSigner(t, "a test key")
+
+  shell := zmq4.NewRouter(ctx)
+  defer shell.Close()
+  err := shell.Listen("tcp://127.0.0.1:0")
+  assert.NoError(t, err, "could not listen on the shell socket")
+
+  frontEnd := zmq4.NewDealer(ctx, zmq4.WithID(zmq4.SocketIdentity("front-end")))
+  defer frontEnd.Close()
+  err = frontEnd.Dial("tcp://" + shell.Addr().String())
+  assert.NoError(t, err, "could not dial the shell socket")
+
+  request, err := NewMsg("comm_info_request", ComposedMsg{})
+  assert.NoError(t, err, "could not create the request")
+  request.Buffers = [][]byte{ []byte("request buffer") }
+  msgParts, err := request.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the request")
+  frames := append([][]byte{ []byte("<IDS|MSG>") }, msgParts...)
+  err = frontEnd.Send(zmq4.NewMsgFrom(frames...))
+  assert.NoError(t, err, "could not send the request")
+
+  wireMsg, err := shell.Recv()
+  assert.NoError(t, err, "could not receive the request")
+  msg, ids, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+  assert.NoError(t, err, "could not decode the request")
+  assert.Equal(t, request.Buffers, msg.Buffers)
+
+  large := make([]byte, 4*1024*1024)
+  _, err = rand.Read(large)
+  assert.NoError(t, err, "could not create a large buffer")
+
+  receipt := MsgReceipt{
+    Msg:        msg,
+    Identities: ids,
+    Sockets:    SocketGroup{
+      ShellSocket: Socket{ Socket: shell, Lock: &sync.Mutex{} },
+      Signer:      signer,
+    },
+  }
+  err = receipt.Reply("comm_info_reply", map[string]interface{}{}, large)
+  assert.NoError(t, err, "could not reply")
+
+  wireMsg, err = frontEnd.Recv()
+  assert.NoError(t, err, "could not receive the reply")
+  reply, _, err := WireMsgToComposedMsg(wireMsg.Frames, signer)
+  assert.NoError(t, err, "could not decode the reply")
+  assert.Equal(t, "comm_info_reply", reply.Header.MsgType)
+  assert.Equal(t, [][]byte{ large }, reply.Buffers)
+}
+
+func TestMalformedWireMsgs(t *testing.T) {
+  signer := testSigner(t, "a test key")
+
+  msg, err := NewMsg("kernel_info_request", ComposedMsg{})
+  assert.NoError(t, err, "could not create the message")
+  signed, err := msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the message")
+  unsigned, err := msg.ToWireMsg(nil)
+  assert.NoError(t, err, "could not encode the message")
+
+  delimiter := []byte("<IDS|MSG>")
+  withFrame := func(msgParts [][]byte, index int, frame string) [][]byte {
+    frames := append([][]byte{ delimiter }, msgParts...)
+    frames[index+1] = []byte(frame)
+    return frames
+  }
+
+  for _, test := range []struct {
+    name   string
+    frames [][]byte
+    signer *MsgSigner
+    err    error
+  }{
+    { "no frames", nil, signer, &MissingDelimiterError{} },
+    { "no delimiter", signed, signer, &MissingDelimiterError{} },
+    { "no frames after the delimiter", [][]byte{ delimiter }, signer,
+      &ShortMessageError{ Frames: 0 } },
+    { "missing content", append([][]byte{ delimiter }, signed[:4]...), signer,
+      &ShortMessageError{ Frames: 4 } },
+    { "non hex signature", withFrame(signed, 0, "not hex"), signer,
+      &InvalidSignatureError{} },
+    { "bad header", withFrame(unsigned, 1, "{"), nil,
+      &InvalidJSONError{ Frame: "header" } },
+    { "bad parent header", withFrame(unsigned, 2, "[]"), nil,
+      &InvalidJSONError{ Frame: "parent header" } },
+    { "bad metadata", withFrame(unsigned, 3, `"metadata"`), nil,
+      &InvalidJSONError{ Frame: "metadata" } },
+    { "non object content", withFrame(unsigned, 4, "42"), nil,
+      &InvalidJSONError{ Frame: "content" } },
+  } {
+    _, _, err := WireMsgToComposedMsg(test.frames, test.signer)
+    assert.IsType(t, test.err, err, test.name)
+    switch expected := test.err.(type) {
+    case *ShortMessageError:
+      assert.Equal(t, expected, err, test.name)
+    case *InvalidJSONError:
+      var jsonErr *InvalidJSONError
+      if assert.True(t, errors.As(err, &jsonErr), test.name) {
+        assert.Equal(t, expected.Frame, jsonErr.Frame, test.name)
+        assert.Error(t, errors.Unwrap(jsonErr), test.name)
+      }
+    }
+  }
+}
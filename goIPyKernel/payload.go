@@ -0,0 +1,90 @@
+package goIPyKernel
+
+import (
+  "errors"
+  "sync"
+)
+
+// Payloads ask the front-end to take an action once an execute_request
+// has been evaluated: to show (long) output in its pager, to set the text
+// of the next input cell or to exit. They are sent in the payload list of
+// the execute_reply.
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#payloads-deprecated
+//
+
+// Payloads collects the payloads (added, through its MsgReceipt, while an
+// execute_request is evaluated) for the execute_reply.
+//
+type Payloads struct {
+  sync.Mutex
+
+  payloads []interface{}
+}
+
+// Add adds a payload (one of the SetNextInputPayload, PagePayload or
+// AskExitPayload contents).
+//
+func (payloads *Payloads) Add(payload interface{}) {
+  payloads.Lock()
+  defer payloads.Unlock()
+
+  payloads.payloads = append(payloads.payloads, payload)
+}
+
+// List returns the payloads in the order they were added.
+//
+func (payloads *Payloads) List() []interface{} {
+  payloads.Lock()
+  defer payloads.Unlock()
+
+  return append([]interface{}{}, payloads.payloads...)
+}
+
+// ErrPayloadsNotAllowed is returned when a payload is added to the
+// receipt of a message which is not an execute_request.
+//
+var ErrPayloadsNotAllowed = errors.New("payloads can only be sent in an execute_reply")
+
+// addPayload adds the payload to the execute_reply of this receipt's
+// execute_request.
+//
+func (receipt *MsgReceipt) addPayload(payload interface{}) error {
+  if receipt.Payloads == nil {
+    return ErrPayloadsNotAllowed
+  }
+  receipt.Payloads.Add(payload)
+  return nil
+}
+
+// SetNextInput asks the front-end to set the text of the next input cell
+// (or, if replace is true, to replace the text of the current cell).
+//
+func (receipt *MsgReceipt) SetNextInput(text string, replace bool) error {
+  return receipt.addPayload(SetNextInputPayload{
+    Source:  "set_next_input",
+    Text:    text,
+    Replace: replace,
+  })
+}
+
+// Page asks the front-end to show the data (which should include a
+// MIMETypeText representation) in its pager, starting at the start line.
+//
+func (receipt *MsgReceipt) Page(data Data, start int) error {
+  return receipt.addPayload(PagePayload{
+    Source: "page",
+    Data:   EnsureMIMEMap(data.Data),
+    Start:  start,
+  })
+}
+
+// AskExit asks the front-end to exit (and, unless keepKernel is true, to
+// shutdown the kernel).
+//
+func (receipt *MsgReceipt) AskExit(keepKernel bool) error {
+  return receipt.addPayload(AskExitPayload{
+    Source:     "ask_exit",
+    KeepKernel: keepKernel,
+  })
+}
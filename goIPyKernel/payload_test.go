@@ -0,0 +1,54 @@
+package goIPyKernel
+
+import(
+  "testing"
+
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestPayloadsNeedAnExecuteRequest(t *testing.T) {
+  receipt := MsgReceipt{}
+  assert.Equal(t, ErrPayloadsNotAllowed, receipt.SetNextInput("text", true))
+  assert.Equal(t, ErrPayloadsNotAllowed, receipt.AskExit(false))
+
+  receipt.Payloads = &Payloads{}
+  assert.NoError(t, receipt.Page(Data{ Data: MIMEMap{ MIMETypeText: "help" } }, 0))
+  assert.Equal(t, []interface{}{
+    PagePayload{
+      Source: "page",
+      Data:   MIMEMap{ MIMETypeText: "help" },
+    },
+  }, receipt.Payloads.List())
+}
+
+func TestExecuteReplyCarriesPayloads(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  reply := request(t, shell, signer, "execute_request",
+    map[string]interface{}{ "code": "exit" },
+  )
+  content := reply.Content.(map[string]interface{})
+  assert.Equal(t, "ok", content["status"])
+  assert.Equal(t, []interface{}{
+    map[string]interface{}{
+      "source":  "set_next_input",
+      "text":    "next",
+      "replace": false,
+    },
+    map[string]interface{}{
+      "source":     "ask_exit",
+      "keepkernel": true,
+    },
+  }, content["payload"])
+
+  // payloads are only sent in the reply of the request which added them
+  reply = request(t, shell, signer, "execute_request",
+    map[string]interface{}{ "code": "ok" },
+  )
+  content = reply.Content.(map[string]interface{})
+  assert.Nil(t, content["payload"])
+}
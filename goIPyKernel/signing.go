@@ -0,0 +1,181 @@
+package goIPyKernel
+
+import (
+  "bytes"
+  "container/list"
+  "crypto/hmac"
+  "crypto/md5"
+  "crypto/sha1"
+  "crypto/sha256"
+  "crypto/sha512"
+  "encoding/hex"
+  "fmt"
+  "hash"
+  "sync"
+)
+
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#the-wire-protocol
+
+// DefaultSignatureScheme is the signature scheme used when a connection
+// file does not specify one.
+//
+const DefaultSignatureScheme = "hmac-sha256"
+
+// signatureHashes maps the supported signature schemes to their hashes.
+//
+var signatureHashes = map[string]func() hash.Hash{
+  "hmac-sha256": sha256.New,
+  "hmac-sha512": sha512.New,
+  "hmac-sha1":   sha1.New,
+  "hmac-md5":    md5.New,
+}
+
+// UnknownSignatureSchemeError is returned when a connection file asks for
+// a signature scheme which is not supported.
+//
+type UnknownSignatureSchemeError struct {
+  Scheme string
+}
+
+func (e *UnknownSignatureSchemeError) Error() string {
+  return fmt.Sprintf(
+    "Unknown signature scheme %q (expected one of hmac-sha256, hmac-sha512, hmac-sha1 or hmac-md5)",
+    e.Scheme,
+  )
+}
+
+// MsgSigner signs (and verifies the signatures of) messages with the HMAC
+// of a signature scheme and a key. A nil MsgSigner, or one with an empty
+// key, neither signs nor verifies messages (as the protocol specifies).
+//
+type MsgSigner struct {
+  Scheme string
+  Key    []byte
+
+  newHash func() hash.Hash
+}
+
+// NewMsgSigner creates a MsgSigner for the (hmac-sha256, hmac-sha512,
+// hmac-sha1 or hmac-md5) scheme and key. An empty scheme is taken to be
+// the DefaultSignatureScheme.
+//
+func NewMsgSigner(scheme string, key []byte) (*MsgSigner, error) {
+  if scheme == "" {
+    scheme = DefaultSignatureScheme
+  }
+  newHash, ok := signatureHashes[scheme]
+  if !ok {
+    return nil, &UnknownSignatureSchemeError{ Scheme: scheme }
+  }
+  return &MsgSigner{ Scheme: scheme, Key: key, newHash: newHash }, nil
+}
+
+// Signs returns true if the signer signs (and verifies) messages.
+//
+func (signer *MsgSigner) Signs() bool {
+  return signer != nil && len(signer.Key) != 0
+}
+
+// Sign returns the (hex encoded) signature of the message frames (or nil
+// if the signer does not sign messages).
+//
+func (signer *MsgSigner) Sign(frames [][]byte) []byte {
+  if !signer.Signs() {
+    return nil
+  }
+  digest := signer.digest(frames)
+  signature := make([]byte, hex.EncodedLen(len(digest)))
+  hex.Encode(signature, digest)
+  return signature
+}
+
+// Verify returns true if the (hex encoded) signature is that of the
+// message frames (or if the signer does not verify messages).
+//
+func (signer *MsgSigner) Verify(signature []byte, frames [][]byte) bool {
+  if !signer.Signs() {
+    return true
+  }
+  decoded := make([]byte, hex.DecodedLen(len(signature)))
+  if _, err := hex.Decode(decoded, signature); err != nil {
+    return false
+  }
+  return hmac.Equal(signer.digest(frames), decoded)
+}
+
+// digest returns the HMAC of the message frames.
+//
+func (signer *MsgSigner) digest(frames [][]byte) []byte {
+  newHash := signer.newHash
+  if newHash == nil {
+    newHash = sha256.New
+  }
+  mac := hmac.New(newHash, signer.Key)
+  for _, frame := range frames {
+    mac.Write(frame)
+  }
+  return mac.Sum(nil)
+}
+
+// DefaultReplayCacheSize is the number of recently seen signatures
+// remembered by the kernel's ReplayCache.
+//
+const DefaultReplayCacheSize = 1 << 16
+
+// ReplayedMsgError is returned when a received message has the signature
+// of a message which was recently received.
+//
+type ReplayedMsgError struct{}
+
+func (e *ReplayedMsgError) Error() string {
+  return "A message had the signature of a recently received message (a replay?)"
+}
+
+// ReplayCache remembers the signatures of the most recently received
+// messages so that replayed messages can be rejected (as the protocol
+// recommends).
+//
+type ReplayCache struct {
+  Mutex sync.Mutex
+  Size  int
+
+  order *list.List
+  seen  map[string]*list.Element
+}
+
+// NewReplayCache creates a ReplayCache which remembers (at most) size
+// signatures.
+//
+func NewReplayCache(size int) *ReplayCache {
+  return &ReplayCache{
+    Size:  size,
+    order: list.New(),
+    seen:  make(map[string]*list.Element),
+  }
+}
+
+// Check records the signature, returning a ReplayedMsgError if it has
+// already been seen. Empty (unsigned) signatures are never rejected.
+// Since Verify accepts upper (as well as lower) case hex, signatures are
+// compared ignoring their case.
+//
+func (cache *ReplayCache) Check(signature []byte) error {
+  if len(signature) == 0 {
+    return nil
+  }
+
+  cache.Mutex.Lock()
+  defer cache.Mutex.Unlock()
+
+  key := string(bytes.ToLower(signature))
+  if _, ok := cache.seen[key]; ok {
+    return &ReplayedMsgError{}
+  }
+  cache.seen[key] = cache.order.PushBack(key)
+  for 0 < cache.Size && cache.Size < cache.order.Len() {
+    oldest := cache.order.Front()
+    cache.order.Remove(oldest)
+    delete(cache.seen, oldest.Value.(string))
+  }
+  return nil
+}
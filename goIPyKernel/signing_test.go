@@ -0,0 +1,117 @@
+package goIPyKernel
+
+import(
+  "bytes"
+  "errors"
+  "testing"
+
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+// testSigner returns an hmac-sha256 MsgSigner with the key.
+//
+func testSigner(t *testing.T, key string) *MsgSigner {
+  signer, err := NewMsgSigner(DefaultSignatureScheme, []byte(key))
+  assert.NoError(t, err, "could not create the signer")
+  return signer
+}
+
+func TestSignatureSchemes(t *testing.T) {
+  msg, err := NewMsg("kernel_info_request", ComposedMsg{})
+  assert.NoError(t, err, "could not create the message")
+
+  for scheme, hexLen := range map[string]int{
+    "hmac-sha256": 64,
+    "hmac-sha512": 128,
+    "hmac-sha1":   40,
+    "hmac-md5":    32,
+  } {
+    signer, err := NewMsgSigner(scheme, []byte("a test key"))
+    assert.NoError(t, err, scheme)
+
+    msgParts, err := msg.ToWireMsg(signer)
+    assert.NoError(t, err, scheme)
+    assert.Len(t, msgParts[0], hexLen, scheme)
+
+    frames := append([][]byte{ []byte("<IDS|MSG>") }, msgParts...)
+    _, _, err = WireMsgToComposedMsg(frames, signer)
+    assert.NoError(t, err, scheme)
+
+    // a message signed with one scheme does not verify with another
+    other := "hmac-sha256"
+    if scheme == other {
+      other = "hmac-sha512"
+    }
+    otherSigner, err := NewMsgSigner(other, []byte("a test key"))
+    assert.NoError(t, err, other)
+    _, _, err = WireMsgToComposedMsg(frames, otherSigner)
+    assert.IsType(t, &InvalidSignatureError{}, err, scheme)
+  }
+
+  signer, err := NewMsgSigner("", nil)
+  assert.NoError(t, err, "the empty scheme should be the default")
+  assert.Equal(t, DefaultSignatureScheme, signer.Scheme)
+  assert.False(t, signer.Signs(), "an empty key should not sign")
+
+  _, err = NewMsgSigner("hmac-sha3", []byte("a test key"))
+  assert.Equal(t, &UnknownSignatureSchemeError{ Scheme: "hmac-sha3" }, err)
+}
+
+func TestPrepareSocketsUnknownScheme(t *testing.T) {
+  _, connInfo := writeConnectionFile(t)
+  connInfo.SignatureScheme = "hmac-sha3"
+
+  _, err := PrepareSockets(connInfo)
+  var schemeErr *UnknownSignatureSchemeError
+  if assert.True(t, errors.As(err, &schemeErr), "expected an unknown scheme") {
+    assert.Equal(t, "hmac-sha3", schemeErr.Scheme)
+  }
+}
+
+func TestReplayCache(t *testing.T) {
+  cache := NewReplayCache(2)
+
+  assert.NoError(t, cache.Check([]byte("a")))
+  assert.NoError(t, cache.Check([]byte("b")))
+  assert.IsType(t, &ReplayedMsgError{}, cache.Check([]byte("a")))
+
+  // unsigned messages are never replays
+  assert.NoError(t, cache.Check(nil))
+  assert.NoError(t, cache.Check(nil))
+
+  // only the most recent signatures are remembered
+  assert.NoError(t, cache.Check([]byte("c")))
+  assert.NoError(t, cache.Check([]byte("a")))
+  assert.IsType(t, &ReplayedMsgError{}, cache.Check([]byte("c")))
+}
+
+func TestDecodeRejectsReplays(t *testing.T) {
+  sockets := SocketGroup{
+    Signer:  testSigner(t, "a test key"),
+    Replays: NewReplayCache(DefaultReplayCacheSize),
+  }
+
+  msg, err := NewMsg("execute_request", ComposedMsg{})
+  assert.NoError(t, err, "could not create the message")
+  msgParts, err := msg.ToWireMsg(sockets.Signer)
+  assert.NoError(t, err, "could not encode the message")
+  frames := append([][]byte{ []byte("front-end"), []byte("<IDS|MSG>") }, msgParts...)
+
+  _, ids, err := sockets.decodeWireMsg(frames)
+  assert.NoError(t, err, "the first message should be accepted")
+  assert.Equal(t, [][]byte{ []byte("front-end") }, ids)
+
+  _, _, err = sockets.decodeWireMsg(frames)
+  assert.IsType(t, &ReplayedMsgError{}, err, "the replayed message should be rejected")
+
+  // a replay whose signature has been re-cased still verifies...
+  recased := append([][]byte{}, frames...)
+  recased[2] = bytes.ToUpper(frames[2])
+  assert.True(t, sockets.Signer.Verify(recased[2], recased[3:7]))
+
+  // ... but is rejected as well
+  _, _, err = sockets.decodeWireMsg(recased)
+  assert.IsType(t, &ReplayedMsgError{}, err, "the re-cased replay should be rejected")
+}
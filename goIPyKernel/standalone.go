@@ -0,0 +1,139 @@
+package goIPyKernel
+
+import (
+  "encoding/json"
+  "io/ioutil"
+  "os"
+  "os/signal"
+  "path/filepath"
+  "runtime"
+  "sync"
+  "syscall"
+
+  "github.com/gofrs/uuid"
+)
+
+// A standalone kernel (one started by hand, without a connection file, for
+// example for debugging) chooses its own (free) ports and key and writes
+// its connection file into the Jupyter runtime directory, so that a
+// front-end can be connected with (for example):
+//
+//   jupyter console --existing <connection file>
+//
+
+// NewConnectionInfo returns the ConnectionInfo of a standalone kernel:
+// the tcp transport on 127.0.0.1 with a random key and (zero) ports which
+// are chosen when the sockets are bound (see BoundConnectionInfo).
+//
+func NewConnectionInfo() (ConnectionInfo, error) {
+  key, err := uuid.NewV4()
+  if err != nil {
+    return ConnectionInfo{}, err
+  }
+  return ConnectionInfo{
+    SignatureScheme: DefaultSignatureScheme,
+    Transport:       "tcp",
+    IP:              "127.0.0.1",
+    Key:             key.String(),
+  }, nil
+}
+
+// RuntimeDir returns Jupyter's runtime directory (in which the connection
+// files of running kernels are kept).
+//
+// This is $JUPYTER_RUNTIME_DIR (if set), otherwise the runtime directory
+// in Jupyter's data directory: $JUPYTER_DATA_DIR (if set), otherwise
+// ~/Library/Jupyter on macOS, %APPDATA%\jupyter on Windows and
+// $XDG_DATA_HOME/jupyter (or ~/.local/share/jupyter) elsewhere.
+//
+func RuntimeDir() (string, error) {
+  if runtimeDir := os.Getenv("JUPYTER_RUNTIME_DIR"); runtimeDir != "" {
+    return runtimeDir, nil
+  }
+
+  dataDir := os.Getenv("JUPYTER_DATA_DIR")
+  if dataDir == "" {
+    homeDir, err := os.UserHomeDir()
+    if err != nil {
+      return "", err
+    }
+    switch runtime.GOOS {
+      case "windows" :
+        dataDir = filepath.Join(os.Getenv("APPDATA"), "jupyter")
+      case "darwin" :
+        dataDir = filepath.Join(homeDir, "Library", "Jupyter")
+      default :
+        xdgDataHome := os.Getenv("XDG_DATA_HOME")
+        if xdgDataHome == "" {
+          xdgDataHome = filepath.Join(homeDir, ".local", "share")
+        }
+        dataDir = filepath.Join(xdgDataHome, "jupyter")
+    }
+  }
+  return filepath.Join(dataDir, "runtime"), nil
+}
+
+// removeConnectionFileOnStop makes sure that a standalone kernel's
+// connFile (which holds the key of a kernel which may still be listening)
+// is not left behind: the file is removed as soon as the process receives
+// a SIGINT or SIGTERM (before stop is called to shutdown the kernel), or
+// when the returned remove function is called (by Run, as the kernel
+// shuts down). A second signal stops the process at once.
+//
+func removeConnectionFileOnStop(connFile string, stop func()) (remove func()) {
+  var removeOnce sync.Once
+  removeFile := func() {
+    removeOnce.Do(func() { os.Remove(connFile) })
+  }
+
+  signals := make(chan os.Signal, 1)
+  signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+  done := make(chan struct{})
+  go func() {
+    select {
+    case <-signals:
+      removeFile()
+      signal.Stop(signals)
+      stop()
+    case <-done:
+    }
+  }()
+
+  var stopOnce sync.Once
+  return func() {
+    stopOnce.Do(func() {
+      signal.Stop(signals)
+      close(done)
+    })
+    removeFile()
+  }
+}
+
+// WriteConnectionFile writes the connInfo, readable only by the user, to a
+// new `kernel-<uuid>.json` connection file in the RuntimeDir and returns
+// its path.
+//
+func WriteConnectionFile(connInfo ConnectionInfo) (string, error) {
+  runtimeDir, err := RuntimeDir()
+  if err != nil {
+    return "", err
+  }
+  if err := os.MkdirAll(runtimeDir, 0700); err != nil {
+    return "", err
+  }
+
+  kernelID, err := uuid.NewV4()
+  if err != nil {
+    return "", err
+  }
+  connFile := filepath.Join(runtimeDir, "kernel-" + kernelID.String() + ".json")
+
+  connData, err := json.MarshalIndent(connInfo, "", "  ")
+  if err != nil {
+    return "", err
+  }
+  if err := ioutil.WriteFile(connFile, connData, 0600); err != nil {
+    return "", err
+  }
+  return connFile, nil
+}
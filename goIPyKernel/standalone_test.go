@@ -0,0 +1,130 @@
+package goIPyKernel
+
+import(
+  "encoding/json"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "syscall"
+  "testing"
+  "time"
+
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+// setRuntimeDir points JUPYTER_RUNTIME_DIR at a new temporary directory
+// (for the duration of the test) and returns it.
+//
+func setRuntimeDir(t *testing.T) string {
+  dir, err := ioutil.TempDir("", "goIPyKernel")
+  assert.NoError(t, err, "could not create a temporary directory")
+  oldRuntimeDir, wasSet := os.LookupEnv("JUPYTER_RUNTIME_DIR")
+  os.Setenv("JUPYTER_RUNTIME_DIR", dir)
+  t.Cleanup(func() {
+    if wasSet {
+      os.Setenv("JUPYTER_RUNTIME_DIR", oldRuntimeDir)
+    } else {
+      os.Unsetenv("JUPYTER_RUNTIME_DIR")
+    }
+    os.RemoveAll(dir)
+  })
+  return dir
+}
+
+func TestRuntimeDir(t *testing.T) {
+  runtimeDir := setRuntimeDir(t)
+  dir, err := RuntimeDir()
+  assert.NoError(t, err)
+  assert.Equal(t, runtimeDir, dir)
+}
+
+func TestWriteConnectionFile(t *testing.T) {
+  runtimeDir := setRuntimeDir(t)
+
+  connInfo, err := NewConnectionInfo()
+  assert.NoError(t, err, "could not create the connection info")
+  assert.Equal(t, "tcp", connInfo.Transport)
+  assert.Equal(t, DefaultSignatureScheme, connInfo.SignatureScheme)
+  assert.NotEmpty(t, connInfo.Key, "a standalone kernel should sign its messages")
+
+  connFile, err := WriteConnectionFile(connInfo)
+  assert.NoError(t, err, "could not write the connection file")
+  assert.Equal(t, runtimeDir, filepath.Dir(connFile))
+
+  info, err := os.Stat(connFile)
+  assert.NoError(t, err, "could not stat the connection file")
+  assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+  connData, err := ioutil.ReadFile(connFile)
+  assert.NoError(t, err, "could not read the connection file")
+  var written ConnectionInfo
+  err = json.Unmarshal(connData, &written)
+  assert.NoError(t, err, "could not decode the connection file")
+  assert.Equal(t, connInfo, written)
+}
+
+// waitForConnectionFile waits (at most a few seconds) for a connection
+// file to appear in the runtimeDir.
+//
+func waitForConnectionFile(t *testing.T, runtimeDir string) string {
+  for tries := 0 ; tries < 100 ; tries++ {
+    connFiles, _ := filepath.Glob(filepath.Join(runtimeDir, "kernel-*.json"))
+    if len(connFiles) > 0 {
+      return connFiles[0]
+    }
+    time.Sleep(50 * time.Millisecond)
+  }
+  t.Fatal("no connection file was written")
+  return ""
+}
+
+func TestStandaloneKernel(t *testing.T) {
+  runtimeDir := setRuntimeDir(t)
+
+  kernel := NewIPyKernel(newTestAdaptor())
+  kernel.History, _ = OpenHistoryFile(tempHistoryPath(t))
+  _, stop := runTestKernel(t, kernel, "")
+
+  connFile := waitForConnectionFile(t, runtimeDir)
+  connData, err := ioutil.ReadFile(connFile)
+  assert.NoError(t, err, "could not read the connection file")
+  var connInfo ConnectionInfo
+  err = json.Unmarshal(connData, &connInfo)
+  assert.NoError(t, err, "could not decode the connection file")
+  assert.NotZero(t, connInfo.ShellPort, "the shell port was not written")
+
+  shell := dialTestSocket(t, "shell", connInfo.ShellPort)
+  defer shell.Close()
+  reply := request(t, shell, testSigner(t, connInfo.Key), "kernel_info_request", nil)
+  assert.Equal(t, "kernel_info_reply", reply.Header.MsgType)
+
+  // the connection file is removed once the kernel stops
+  stop()
+  _, err = os.Stat(connFile)
+  assert.True(t, os.IsNotExist(err), "the connection file was not removed")
+}
+
+func TestConnectionFileIsRemovedOnASignal(t *testing.T) {
+  setRuntimeDir(t)
+  connInfo, err := NewConnectionInfo()
+  assert.NoError(t, err, "could not create the connection info")
+  connFile, err := WriteConnectionFile(connInfo)
+  assert.NoError(t, err, "could not write the connection file")
+
+  stopped := make(chan struct{})
+  remove := removeConnectionFileOnStop(connFile, func() { close(stopped) })
+  defer remove()
+
+  process, err := os.FindProcess(os.Getpid())
+  assert.NoError(t, err, "could not find the test process")
+  if err := process.Signal(syscall.SIGTERM); err != nil {
+    t.Skipf("can not signal the test process: %v", err)
+  }
+
+  // the connection file is removed before the kernel is asked to stop
+  waitForStop(t, stopped, "the kernel was not stopped by the signal")
+  _, err = os.Stat(connFile)
+  assert.True(t, os.IsNotExist(err), "the connection file was not removed")
+}
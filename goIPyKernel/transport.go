@@ -0,0 +1,134 @@
+package goIPyKernel
+
+import (
+  "fmt"
+  "net"
+  "os"
+  "strconv"
+  "strings"
+
+  "github.com/go-zeromq/zmq4"
+)
+
+// A kernel's sockets are bound using either the tcp transport (to ports
+// of the connection file's IP address) or the ipc transport (to Unix
+// domain sockets). As in Jupyter, the path of an ipc socket is the
+// connection file's "ip" followed by a dash and the socket's "port"
+// number, for example `kernel-ipc-1`.
+//
+// see: https://jupyter-client.readthedocs.io/en/stable/kernels.html#connection-files
+//
+
+// UnknownTransportError is returned when a connection file asks for a
+// transport which is not supported.
+//
+type UnknownTransportError struct {
+  Transport string
+}
+
+func (e *UnknownTransportError) Error() string {
+  return fmt.Sprintf(
+    "Unknown transport %q (expected one of tcp or ipc)",
+    e.Transport,
+  )
+}
+
+// Endpoint returns the zmq endpoint, for the connInfo's transport and IP,
+// of the socket bound to the port. (An empty transport is taken to be
+// tcp).
+//
+func Endpoint(connInfo ConnectionInfo, port int) (string, error) {
+  switch connInfo.Transport {
+    case "tcp", "" :
+      return fmt.Sprintf("tcp://%v:%v", connInfo.IP, port), nil
+    case "ipc" :
+      return fmt.Sprintf("ipc://%v-%v", connInfo.IP, port), nil
+  }
+  return "", &UnknownTransportError{ Transport: connInfo.Transport }
+}
+
+// AssignIPCPorts returns the (ipc) connInfo with each of its unassigned
+// (zero) ports replaced, as Jupyter does, by the lowest port number
+// (from 1) whose socket path is neither in use nor already assigned.
+//
+func AssignIPCPorts(connInfo ConnectionInfo) ConnectionInfo {
+  ports := []*int{
+    &connInfo.ShellPort, &connInfo.ControlPort, &connInfo.StdinPort,
+    &connInfo.IOPubPort, &connInfo.HBPort,
+  }
+
+  assigned := map[int]bool{}
+  for _, port := range ports {
+    assigned[*port] = true
+  }
+
+  nextPort := 1
+  for _, port := range ports {
+    if *port != 0 {
+      continue
+    }
+    for {
+      _, err := os.Stat(fmt.Sprintf("%v-%v", connInfo.IP, nextPort))
+      if !assigned[nextPort] && os.IsNotExist(err) {
+        break
+      }
+      nextPort++
+    }
+    *port = nextPort
+    assigned[nextPort] = true
+  }
+  return connInfo
+}
+
+// listenOn binds the socket to the port using the connInfo's transport.
+// The Unix domain socket of an ipc socket is made accessible only to the
+// user (it is created under a restricted umask, so that it is never, even
+// briefly, accessible to anyone else). If its permissions can not be
+// ensured, the socket is closed and its Unix domain socket removed.
+//
+func listenOn(socket zmq4.Socket, connInfo ConnectionInfo, port int) error {
+  endpoint, err := Endpoint(connInfo, port)
+  if err != nil {
+    return err
+  }
+  if connInfo.Transport != "ipc" {
+    return socket.Listen(endpoint)
+  }
+
+  restoreUmask := restrictUmask()
+  err = socket.Listen(endpoint)
+  restoreUmask()
+  if err != nil {
+    return err
+  }
+
+  path := strings.TrimPrefix(endpoint, "ipc://")
+  if err := os.Chmod(path, 0600); err != nil {
+    socket.Close()
+    os.Remove(path)
+    return err
+  }
+  return nil
+}
+
+// boundPort returns the port to which a socket is bound at the addr (the
+// tcp port, or the number following the last dash of an ipc socket's
+// path), or zero if it can not be determined.
+//
+func boundPort(addr net.Addr) int {
+  switch addr := addr.(type) {
+    case *net.TCPAddr :
+      return addr.Port
+    case *net.UnixAddr :
+      dash := strings.LastIndex(addr.Name, "-")
+      if dash < 0 {
+        return 0
+      }
+      port, err := strconv.Atoi(addr.Name[dash+1:])
+      if err != nil {
+        return 0
+      }
+      return port
+  }
+  return 0
+}
@@ -0,0 +1,110 @@
+package goIPyKernel
+
+import(
+  "context"
+  "errors"
+  "io/ioutil"
+  "os"
+  "path/filepath"
+  "testing"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+// ipcConnectionInfo returns the ConnectionInfo of ipc sockets in a
+// temporary directory.
+//
+func ipcConnectionInfo(t *testing.T) ConnectionInfo {
+  dir, err := ioutil.TempDir("", "goIPyKernel")
+  assert.NoError(t, err, "could not create a temporary directory")
+  t.Cleanup(func() { os.RemoveAll(dir) })
+
+  return ConnectionInfo{
+    SignatureScheme: "hmac-sha256",
+    Transport:       "ipc",
+    IP:              filepath.Join(dir, "kernel-ipc"),
+    Key:             "a test key",
+  }
+}
+
+func TestEndpoint(t *testing.T) {
+  endpoint, err := Endpoint(ConnectionInfo{ Transport: "tcp", IP: "127.0.0.1" }, 1234)
+  assert.NoError(t, err)
+  assert.Equal(t, "tcp://127.0.0.1:1234", endpoint)
+
+  endpoint, err = Endpoint(ConnectionInfo{ Transport: "ipc", IP: "/tmp/kernel-ipc" }, 3)
+  assert.NoError(t, err)
+  assert.Equal(t, "ipc:///tmp/kernel-ipc-3", endpoint)
+
+  _, err = Endpoint(ConnectionInfo{ Transport: "udp", IP: "127.0.0.1" }, 1234)
+  var unknownTransport *UnknownTransportError
+  assert.True(t, errors.As(err, &unknownTransport), "udp should be unknown")
+  assert.Equal(t, "udp", unknownTransport.Transport)
+}
+
+func TestAssignIPCPorts(t *testing.T) {
+  connInfo := ipcConnectionInfo(t)
+  err := ioutil.WriteFile(connInfo.IP + "-1", nil, 0600)
+  assert.NoError(t, err, "could not create an existing socket path")
+  connInfo.StdinPort = 3
+
+  connInfo = AssignIPCPorts(connInfo)
+  assert.Equal(t, 2, connInfo.ShellPort)
+  assert.Equal(t, 4, connInfo.ControlPort)
+  assert.Equal(t, 3, connInfo.StdinPort)
+  assert.Equal(t, 5, connInfo.IOPubPort)
+  assert.Equal(t, 6, connInfo.HBPort)
+}
+
+func TestPrepareSocketsRejectsUnknownTransports(t *testing.T) {
+  _, err := PrepareSockets(ConnectionInfo{ Transport: "udp", IP: "127.0.0.1" })
+  var unknownTransport *UnknownTransportError
+  assert.True(t, errors.As(err, &unknownTransport), "udp should be rejected")
+}
+
+func TestKernelTransports(t *testing.T) {
+  _, tcpConnInfo := writeConnectionFile(t)
+  ipcConnInfo := ipcConnectionInfo(t)
+  ipcConnInfo.ShellPort   = 1
+  ipcConnInfo.ControlPort = 2
+  ipcConnInfo.StdinPort   = 3
+  ipcConnInfo.IOPubPort   = 4
+  ipcConnInfo.HBPort      = 5
+
+  for _, connInfo := range []ConnectionInfo{ tcpConnInfo, ipcConnInfo } {
+    connInfo := connInfo
+    t.Run(connInfo.Transport, func(t *testing.T) {
+      connFile := writeConnectionInfo(t, connInfo)
+      signer := testSigner(t, connInfo.Key)
+
+      kernel := NewIPyKernel(newTestAdaptor())
+      kernel.History, _ = OpenHistoryFile(tempHistoryPath(t))
+      _, stop := runTestKernel(t, kernel, connFile)
+
+      shellEndpoint, err := Endpoint(connInfo, connInfo.ShellPort)
+      assert.NoError(t, err)
+      shell := zmq4.NewDealer(context.Background())
+      defer shell.Close()
+      err = shell.Dial(shellEndpoint)
+      assert.NoError(t, err, "could not dial the shell socket")
+      reply := request(t, shell, signer, "kernel_info_request", nil)
+      assert.Equal(t, "kernel_info_reply", reply.Header.MsgType)
+
+      if connInfo.Transport == "ipc" {
+        info, err := os.Stat(connInfo.IP + "-1")
+        assert.NoError(t, err, "could not stat the shell socket")
+        assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+      }
+
+      stop()
+
+      if connInfo.Transport == "ipc" {
+        _, err := os.Stat(connInfo.IP + "-1")
+        assert.True(t, os.IsNotExist(err), "the shell socket was not removed")
+      }
+    })
+  }
+}
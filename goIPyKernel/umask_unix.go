@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package goIPyKernel
+
+import (
+  "syscall"
+)
+
+// restrictUmask sets the process's umask so that any files (and Unix 
+// domain sockets) created are accessible only to the user, returning a 
+// function which restores the previous umask. 
+//
+// (The umask is shared by the whole process, so it should only be 
+// restricted for as long as it takes to create the files). 
+//
+func restrictUmask() (restore func()) {
+  previous := syscall.Umask(0077)
+  return func() { syscall.Umask(previous) }
+}
@@ -0,0 +1,52 @@
+//go:build !windows
+// +build !windows
+
+package goIPyKernel
+
+import(
+  "context"
+  "os"
+  "strings"
+  "syscall"
+  "testing"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+// bindPermsSocket records the permissions of its Unix domain socket as 
+// soon as it is bound (before listenOn can chmod it). 
+//
+type bindPermsSocket struct {
+  zmq4.Socket
+  perms os.FileMode
+}
+
+func (socket *bindPermsSocket) Listen(endpoint string) error {
+  if err := socket.Socket.Listen(endpoint); err != nil {
+    return err
+  }
+  info, err := os.Stat(strings.TrimPrefix(endpoint, "ipc://"))
+  if err != nil {
+    return err
+  }
+  socket.perms = info.Mode().Perm()
+  return nil
+}
+
+func TestListenOnBindsIPCSocketsUnderARestrictedUmask(t *testing.T) {
+  previous := syscall.Umask(0)
+  defer syscall.Umask(previous)
+
+  connInfo := ipcConnectionInfo(t)
+  socket := &bindPermsSocket{ Socket: zmq4.NewRep(context.Background()) }
+  defer socket.Close()
+
+  assert.NoError(t, listenOn(socket, connInfo, 1))
+  assert.Equal(t, os.FileMode(0), socket.perms&0077,
+    "the ipc socket was bound accessible to others")
+
+  assert.Equal(t, 0, syscall.Umask(0), "the umask was not restored")
+}
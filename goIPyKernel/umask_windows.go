@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package goIPyKernel
+
+// restrictUmask does nothing, as Windows has no umask. 
+//
+func restrictUmask() (restore func()) {
+  return func() {}
+}
@@ -0,0 +1,110 @@
+package goIPyKernel
+
+import (
+  "strconv"
+  "strings"
+  "unicode/utf8"
+)
+
+// see: https://jupyter-client.readthedocs.io/en/stable/messaging.html#versioning
+
+// NegotiateVersion returns the protocol version with which to answer a
+// client which uses the clientVersion: the client's version if it is an
+// older 5.x version, otherwise (and for unknown versions) the kernel's
+// ProtocolVersion.
+//
+func NegotiateVersion(clientVersion string) string {
+  clientMajor, clientMinor, ok := parseVersion(clientVersion)
+  if !ok {
+    return ProtocolVersion
+  }
+  major, minor, _ := parseVersion(ProtocolVersion)
+  if clientMajor == major && clientMinor < minor {
+    return strconv.Itoa(clientMajor) + "." + strconv.Itoa(clientMinor)
+  }
+  return ProtocolVersion
+}
+
+// VersionAtLeast returns true if the (major.minor[.patch]) version is the
+// minimum version or later. Unknown versions are taken to be the kernel's
+// ProtocolVersion.
+//
+func VersionAtLeast(version, minimum string) bool {
+  major, minor, ok := parseVersion(version)
+  if !ok {
+    major, minor, _ = parseVersion(ProtocolVersion)
+  }
+  minMajor, minMinor, _ := parseVersion(minimum)
+  return major > minMajor || (major == minMajor && minor >= minMinor)
+}
+
+// parseVersion parses the major and minor numbers of a
+// major.minor[.patch] version.
+//
+func parseVersion(version string) (major, minor int, ok bool) {
+  parts := strings.SplitN(version, ".", 3)
+  if len(parts) < 2 {
+    return 0, 0, false
+  }
+  major, err := strconv.Atoi(parts[0])
+  if err != nil {
+    return 0, 0, false
+  }
+  minor, err = strconv.Atoi(parts[1])
+  if err != nil {
+    return 0, 0, false
+  }
+  return major, minor, true
+}
+
+// Before protocol 5.2 cursor positions were counted in UTF-16 code units
+// (rather than unicode code points).
+//
+const codePointCursorVersion = "5.2"
+
+// cursorFromClient converts a cursor position in the code sent by a
+// client using the version into unicode code points.
+//
+func cursorFromClient(version, code string, cursorPos int) int {
+  if VersionAtLeast(version, codePointCursorVersion) {
+    return cursorPos
+  }
+  units := 0
+  codePoints := 0
+  for _, r := range code {
+    if units >= cursorPos {
+      break
+    }
+    units += utf16Len(r)
+    codePoints++
+  }
+  return codePoints
+}
+
+// cursorToClient converts a cursor position (in unicode code points) in
+// the code into the units of a client using the version.
+//
+func cursorToClient(version, code string, cursorPos int) int {
+  if VersionAtLeast(version, codePointCursorVersion) {
+    return cursorPos
+  }
+  units := 0
+  codePoints := 0
+  for _, r := range code {
+    if codePoints >= cursorPos {
+      break
+    }
+    units += utf16Len(r)
+    codePoints++
+  }
+  return units
+}
+
+// utf16Len returns the number of UTF-16 code units which encode the rune.
+//
+func utf16Len(r rune) int {
+  if r >= 0x10000 && r <= utf8.MaxRune {
+    return 2
+  }
+  return 1
+}
@@ -0,0 +1,84 @@
+package goIPyKernel
+
+import(
+  "testing"
+  "time"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/stretchr/testify/assert"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestNegotiateVersion(t *testing.T) {
+  assert.Equal(t, "5.0", NegotiateVersion("5.0"))
+  assert.Equal(t, "5.2", NegotiateVersion("5.2.1"))
+  assert.Equal(t, ProtocolVersion, NegotiateVersion(ProtocolVersion))
+  assert.Equal(t, ProtocolVersion, NegotiateVersion("5.9"))
+  assert.Equal(t, ProtocolVersion, NegotiateVersion("4.1"))
+  assert.Equal(t, ProtocolVersion, NegotiateVersion(""))
+  assert.Equal(t, ProtocolVersion, NegotiateVersion("five"))
+
+  assert.True(t, VersionAtLeast("5.2", "5.2"))
+  assert.True(t, VersionAtLeast("6.0", "5.2"))
+  assert.True(t, VersionAtLeast("", "5.2"))
+  assert.False(t, VersionAtLeast("5.1.3", "5.2"))
+}
+
+func TestNewMsgHeader(t *testing.T) {
+  parent, err := NewMsg("execute_request", ComposedMsg{})
+  assert.NoError(t, err, "could not create the parent")
+  assert.Equal(t, ProtocolVersion, parent.Header.ProtocolVersion)
+  date, err := time.Parse(TimestampFormat, parent.Header.Timestamp)
+  assert.NoError(t, err, "the date should be ISO 8601")
+  assert.Len(t, parent.Header.Timestamp, len("2006-01-02T15:04:05.000000Z"))
+  assert.WithinDuration(t, time.Now(), date, time.Minute)
+
+  // replies to older clients use the client's version
+  parent.Header.ProtocolVersion = "5.0"
+  reply, err := NewMsg("execute_reply", parent)
+  assert.NoError(t, err, "could not create the reply")
+  assert.Equal(t, "5.0", reply.Header.ProtocolVersion)
+}
+
+func TestCursorUnits(t *testing.T) {
+  code := "a😀b"
+
+  // code points are used from protocol 5.2...
+  assert.Equal(t, 2, cursorFromClient("5.3", code, 2))
+  assert.Equal(t, 2, cursorToClient("5.2", code, 2))
+
+  // ... and UTF-16 code units before
+  assert.Equal(t, 2, cursorFromClient("5.1", code, 3))
+  assert.Equal(t, 3, cursorToClient("5.0", code, 2))
+  assert.Equal(t, 4, cursorToClient("5.0", code, 3))
+}
+
+func TestKernelInfoReply(t *testing.T) {
+  _, frontEnd, stop := startTestKernel(t)
+  defer stop()
+  shell, signer := frontEnd.shell, frontEnd.signer
+
+  reply := request(t, shell, signer, "kernel_info_request", nil)
+  assert.Equal(t, ProtocolVersion, reply.Header.ProtocolVersion)
+  content := reply.Content.(map[string]interface{})
+  assert.Equal(t, "ok", content["status"])
+  assert.Equal(t, ProtocolVersion, content["protocol_version"])
+  assert.Equal(t, false, content["debugger"])
+
+  // older clients are answered with their own version
+  msg, err := NewMsg("kernel_info_request", ComposedMsg{})
+  assert.NoError(t, err, "could not create the request")
+  msg.Header.ProtocolVersion = "5.0"
+  msg.Content = map[string]interface{}{}
+  msgParts, err := msg.ToWireMsg(signer)
+  assert.NoError(t, err, "could not encode the request")
+  err = shell.Send(zmq4.NewMsgFrom(append([][]byte{ []byte("<IDS|MSG>") }, msgParts...)...))
+  assert.NoError(t, err, "could not send the request")
+  wireMsg, err := shell.Recv()
+  assert.NoError(t, err, "could not receive the reply")
+  reply, _, err = WireMsgToComposedMsg(wireMsg.Frames, signer)
+  assert.NoError(t, err, "could not decode the reply")
+  assert.Equal(t, "5.0", reply.Header.ProtocolVersion)
+  assert.Equal(t, "5.0", reply.Content.(map[string]interface{})["protocol_version"])
+}
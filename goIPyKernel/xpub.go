@@ -0,0 +1,346 @@
+package goIPyKernel
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "net"
+  "strings"
+  "sync"
+
+  "github.com/go-zeromq/zmq4"
+  "github.com/go-zeromq/zmq4/security/null"
+  "github.com/gofrs/uuid"
+  "golang.org/x/xerrors"
+)
+
+// zmq4's XPUB socket (in v0.9.0, the version this module requires)
+// handles its subscriptions itself and never passes them on to Recv, so
+// the kernel could never welcome its IOPub subscribers. The xpubSocket is
+// a (minimal) XPUB socket, built on zmq4's ZMTP connections, which both
+// honours the subscriptions it receives and passes them on to Recv.
+//
+// As a ZeroMQ XPUB socket does, once a subscriber has XPubHighWaterMark
+// messages queued (because it is slow, or has stalled) any further
+// messages for it are dropped, so that one subscriber can never block the
+// kernel's publishing.
+//
+// see: https://rfc.zeromq.org/spec/29/ (the XPUB socket type)
+//
+
+// ErrXPubDial is returned by an xpubSocket's Dial (the kernel's IOPub
+// socket only ever listens).
+//
+var ErrXPubDial = errors.New("an xpub socket can only listen")
+
+// XPubHighWaterMark is the number of messages queued for a subscriber
+// (ZeroMQ's default ZMQ_SNDHWM) beyond which its messages are dropped.
+//
+const XPubHighWaterMark = 1000
+
+// xpubConn is the connection of one subscriber (with its subscribed
+// topics and its queue of messages waiting to be sent).
+//
+type xpubConn struct {
+  conn   *zmq4.Conn
+  topics map[string]bool
+  queue  chan zmq4.Msg
+}
+
+// enqueue queues the msg for the subscriber, dropping it if the
+// subscriber's queue is full.
+//
+func (xconn *xpubConn) enqueue(msg zmq4.Msg) {
+  select {
+  case xconn.queue <- msg:
+  default:
+  }
+}
+
+// xpubSubscription is an (un)subscription (a 1, or 0, byte followed by
+// the topic) together with the subscriber which sent it.
+//
+type xpubSubscription struct {
+  frame []byte
+  xconn *xpubConn
+}
+
+// subscribed returns true if the subscriber has subscribed to a prefix of
+// the topic.
+//
+func (xconn *xpubConn) subscribed(topic string) bool {
+  for prefix := range xconn.topics {
+    if strings.HasPrefix(topic, prefix) {
+      return true
+    }
+  }
+  return false
+}
+
+type xpubSocket struct {
+  ctx    context.Context
+  cancel context.CancelFunc
+  id     zmq4.SocketIdentity
+  subs   chan xpubSubscription
+
+  mu       sync.Mutex
+  listener net.Listener
+  conns    map[*xpubConn]bool
+}
+
+// NewXPubSocket returns a new (unbound) XPUB socket whose Recv returns
+// each of the subscriptions (a 1 byte followed by the topic) and
+// unsubscriptions (a 0 byte followed by the topic) it receives.
+//
+func NewXPubSocket(ctx context.Context) zmq4.Socket {
+  ctx, cancel := context.WithCancel(ctx)
+  id, _ := uuid.NewV4()
+  return &xpubSocket{
+    ctx:    ctx,
+    cancel: cancel,
+    id:     zmq4.SocketIdentity(id.String()),
+    subs:   make(chan xpubSubscription),
+    conns:  map[*xpubConn]bool{},
+  }
+}
+
+// Close closes the socket's listener and the connections of all of its
+// subscribers.
+//
+func (xpub *xpubSocket) Close() error {
+  xpub.cancel()
+
+  xpub.mu.Lock()
+  defer xpub.mu.Unlock()
+
+  var err error
+  if xpub.listener != nil {
+    err = xpub.listener.Close()
+  }
+  for xconn := range xpub.conns {
+    xconn.conn.Close()
+  }
+  xpub.conns = map[*xpubConn]bool{}
+  return err
+}
+
+// Send queues the msg for each subscriber subscribed to (a prefix of)
+// its topic (its first frame), without waiting for it to be sent.
+//
+func (xpub *xpubSocket) Send(msg zmq4.Msg) error {
+  if len(msg.Frames) == 0 {
+    return nil
+  }
+  topic := string(msg.Frames[0])
+
+  xpub.mu.Lock()
+  defer xpub.mu.Unlock()
+
+  for xconn := range xpub.conns {
+    if xconn.subscribed(topic) {
+      xconn.enqueue(msg)
+    }
+  }
+  return nil
+}
+
+// sendTo queues the msg for the one subscriber (whatever its
+// subscriptions), if it is still connected.
+//
+func (xpub *xpubSocket) sendTo(xconn *xpubConn, msg zmq4.Msg) {
+  xpub.mu.Lock()
+  defer xpub.mu.Unlock()
+
+  if xpub.conns[xconn] {
+    xconn.enqueue(msg)
+  }
+}
+
+// SendMulti sends the (multipart) msg as Send does.
+//
+func (xpub *xpubSocket) SendMulti(msg zmq4.Msg) error {
+  return xpub.Send(msg)
+}
+
+// Recv returns the next (un)subscription received from any subscriber.
+//
+func (xpub *xpubSocket) Recv() (zmq4.Msg, error) {
+  sub, err := xpub.recvSubscription()
+  if err != nil {
+    return zmq4.Msg{}, err
+  }
+  return zmq4.NewMsg(sub.frame), nil
+}
+
+// recvSubscription returns the next (un)subscription received from any
+// subscriber, together with that subscriber (see sendTo).
+//
+func (xpub *xpubSocket) recvSubscription() (xpubSubscription, error) {
+  select {
+  case sub := <-xpub.subs:
+    return sub, nil
+  case <-xpub.ctx.Done():
+    return xpubSubscription{}, xpub.ctx.Err()
+  }
+}
+
+// Listen binds the socket to a tcp or ipc endpoint and accepts its
+// subscribers' connections (until the socket is closed).
+//
+func (xpub *xpubSocket) Listen(endpoint string) error {
+  parts := strings.SplitN(endpoint, "://", 2)
+  if len(parts) != 2 {
+    return fmt.Errorf("invalid endpoint %q", endpoint)
+  }
+  network, addr := parts[0], parts[1]
+  switch network {
+    case "tcp" :
+      addr = strings.Replace(addr, "*:", "0.0.0.0:", 1)
+    case "ipc" :
+      network = "unix"
+    default :
+      return &UnknownTransportError{ Transport: network }
+  }
+
+  listener, err := net.Listen(network, addr)
+  if err != nil {
+    return xerrors.Errorf("could not listen to %q: %w", endpoint, err)
+  }
+  xpub.mu.Lock()
+  xpub.listener = listener
+  xpub.mu.Unlock()
+
+  go xpub.accept(listener)
+  return nil
+}
+
+// accept opens a ZMTP connection with each new subscriber.
+//
+func (xpub *xpubSocket) accept(listener net.Listener) {
+  for {
+    netConn, err := listener.Accept()
+    if err != nil {
+      return
+    }
+    go xpub.serve(netConn)
+  }
+}
+
+// serve records (and passes on to Recv) each of the (un)subscriptions
+// received from a subscriber, and sends its queued messages, until its
+// connection fails.
+//
+func (xpub *xpubSocket) serve(netConn net.Conn) {
+  conn, err := zmq4.Open(netConn, null.Security(), zmq4.XPub, xpub.id, true, nil)
+  if err != nil {
+    netConn.Close()
+    return
+  }
+  xconn := &xpubConn{
+    conn:   conn,
+    topics: map[string]bool{},
+    queue:  make(chan zmq4.Msg, XPubHighWaterMark),
+  }
+
+  xpub.mu.Lock()
+  if xpub.ctx.Err() != nil {
+    xpub.mu.Unlock()
+    conn.Close()
+    return
+  }
+  xpub.conns[xconn] = true
+  xpub.mu.Unlock()
+
+  done := make(chan struct{})
+  defer func() {
+    xpub.mu.Lock()
+    delete(xpub.conns, xconn)
+    xpub.mu.Unlock()
+    close(done)
+    conn.Close()
+  }()
+  go xpub.write(xconn, done)
+
+  for {
+    msg, err := conn.RecvMsg()
+    if err != nil {
+      return
+    }
+    if len(msg.Frames) != 1 || len(msg.Frames[0]) == 0 {
+      continue
+    }
+    frame := msg.Frames[0]
+    xpub.mu.Lock()
+    switch frame[0] {
+      case 0 :
+        delete(xconn.topics, string(frame[1:]))
+      case 1 :
+        xconn.topics[string(frame[1:])] = true
+      default :
+        xpub.mu.Unlock()
+        continue
+    }
+    xpub.mu.Unlock()
+
+    select {
+    case xpub.subs <- xpubSubscription{ frame: frame, xconn: xconn }:
+    case <-xpub.ctx.Done():
+      return
+    }
+  }
+}
+
+// write sends the subscriber's queued messages (until the done channel
+// is closed). A failed send closes the connection (so that serve drops
+// the subscriber).
+//
+func (xpub *xpubSocket) write(xconn *xpubConn, done <-chan struct{}) {
+  for {
+    select {
+    case msg := <-xconn.queue:
+      if err := xconn.conn.SendMsg(msg); err != nil {
+        xconn.conn.Close()
+        return
+      }
+    case <-done:
+      return
+    }
+  }
+}
+
+// Dial is not supported (see ErrXPubDial).
+//
+func (xpub *xpubSocket) Dial(endpoint string) error {
+  return ErrXPubDial
+}
+
+// Type returns zmq4.XPub.
+//
+func (xpub *xpubSocket) Type() zmq4.SocketType {
+  return zmq4.XPub
+}
+
+// Addr returns the listener's address (or nil if the socket is not yet
+// listening).
+//
+func (xpub *xpubSocket) Addr() net.Addr {
+  xpub.mu.Lock()
+  defer xpub.mu.Unlock()
+
+  if xpub.listener == nil {
+    return nil
+  }
+  return xpub.listener.Addr()
+}
+
+// GetOption is not supported (there are no xpubSocket options).
+//
+func (xpub *xpubSocket) GetOption(name string) (interface{}, error) {
+  return nil, zmq4.ErrBadProperty
+}
+
+// SetOption is not supported (there are no xpubSocket options).
+//
+func (xpub *xpubSocket) SetOption(name string, value interface{}) error {
+  return zmq4.ErrBadProperty
+}
@@ -11,12 +11,13 @@ import(
 	"io"
 	"io/ioutil"
 	"log"
-//	"os"
+	"os"
 	"os/exec"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 //	"time"
 
   tk "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
@@ -37,11 +38,20 @@ const (
 )
 
 type GoAdaptor struct {
+	// irLock guards the replacement of the interpreter (ir, display and 
+	// render) by Restart against its use by Interrupt (which is called 
+	// from the kernel's control goroutine) 
+	irLock  sync.Mutex
 	ir      *gomacro.Interp
 	display *gomacro.Import
 	// map name -> HTMLer, JSONer, Renderer...
 	// used to convert interpreted types to one of these interfaces
 	render map[string]xreflect.Type
+	// evaluating is non-zero (accessed atomically) while EvaluateCode runs
+	evaluating int32
+	// receipt of the execute_request being evaluated (or nil), used by the
+	// special commands to send execute_reply payloads
+	receipt *tk.MsgReceipt
 }
 
 func NewGoAdaptor() *GoAdaptor {
@@ -64,10 +74,37 @@ func NewGoAdaptor() *GoAdaptor {
 	// its value to the closure that holds a reference to msgReceipt
 	ir.DeclVar("Display", nil, stubDisplay)
 
+  // Inject the stub "ClearOutput" function, which clears the outputs of 
+  // the current cell (if wait is true, only once the next output arrives), 
+  // for example between the frames of an animation. 
+  //
+  ir.DeclVar("ClearOutput", nil, stubClearOutput)
+
+  // Inject the stub "Stdin" reader and "ReadLine" function. Since 
+  // os.Stdin must be an *os.File, interpreted code which wants to read 
+  // input from the front-end must use these instead (for example 
+  // `bufio.NewScanner(Stdin)` or `ReadLine("name? ", false)`).
+  //
+  ir.DeclVar("Stdin", nil, stubStdin)
+  ir.DeclVar("ReadLine", nil, stubReadLine)
+
+  // Inject the stub "NewDisplayHandle" function, whose handles display 
+  // outputs which can later be updated in place (for example 
+  // `h, _ := NewDisplayHandle(); h.Display(display.Markdown("0%"))` and 
+  // then `h.Update(display.Markdown("100%"))`). 
+  //
+  ir.DeclVar("NewDisplayHandle", nil, stubNewDisplayHandle)
+
+  // Inject a stub "Comms" manager, replaced by the kernel's CommManager 
+  // in RegisterComms, so that interpreted code can open comms and 
+  // register comm targets (for example 
+  // `Comms.RegisterTarget("name", func(comm *display.Comm, data map[string]interface{}, buffers [][]byte) {...})`).
+  //
+  ir.DeclVar("Comms", nil, tk.NewCommManager())
+
   adaptor := GoAdaptor{
-		ir,
-		display,
-		nil,
+		ir:      ir,
+		display: display,
 	}
 	adaptor.initRenderers()
   return &adaptor
@@ -112,6 +149,25 @@ func (adaptor *GoAdaptor) SetupDisplayCallback(receipt tk.MsgReceipt) {
 	ir := adaptor.ir
 	displayPlace := ir.ValueOf("Display")
 	displayPlace.Set(reflect.ValueOf(receipt.PublishDisplayData))
+  ir.ValueOf("ClearOutput").Set(reflect.ValueOf(receipt.PublishClearOutput))
+
+  // inject the actual "Stdin" and "ReadLine" which request input from Jupyter
+  stdinReceipt := receipt
+  ir.ValueOf("Stdin").Set(reflect.ValueOf(tk.NewJupyterStdinReader(&stdinReceipt)))
+  ir.ValueOf("ReadLine").Set(reflect.ValueOf(stdinReceipt.ReadLine))
+
+  // inject the actual "NewDisplayHandle" whose handles publish to Jupyter
+  ir.ValueOf("NewDisplayHandle").Set(reflect.ValueOf(stdinReceipt.NewDisplayHandle))
+
+  // remember the receipt for the special commands' payloads
+  adaptor.receipt = &stdinReceipt
+}
+
+// RegisterComms gives the interpreted code (as "Comms") the kernel's 
+// CommManager. 
+//
+func (adaptor *GoAdaptor) RegisterComms(comms *tk.CommManager) {
+  adaptor.ir.ValueOf("Comms").Set(reflect.ValueOf(comms))
 }
 
 func (adaptor *GoAdaptor) TeardownDisplayCallback() {
@@ -119,6 +175,13 @@ func (adaptor *GoAdaptor) TeardownDisplayCallback() {
 	ir := adaptor.ir
 	displayPlace := ir.ValueOf("Display")
   displayPlace.Set(reflect.ValueOf(stubDisplay))
+  ir.ValueOf("ClearOutput").Set(reflect.ValueOf(stubClearOutput))
+
+  ir.ValueOf("Stdin").Set(reflect.ValueOf(stubStdin))
+  ir.ValueOf("ReadLine").Set(reflect.ValueOf(stubReadLine))
+  ir.ValueOf("NewDisplayHandle").Set(reflect.ValueOf(stubNewDisplayHandle))
+
+  adaptor.receipt = nil
 }
 
 // doEval evaluates the code in the interpreter. This function captures an 
@@ -130,17 +193,21 @@ func (adaptor *GoAdaptor) EvaluateCode(
   code           string,
 ) (rtnData tk.Data, err error) {
   ir := adaptor.ir
+
+  // Allow Interrupt to interrupt (only) this evaluation.
+  //
+  atomic.StoreInt32(&adaptor.evaluating, 1)
+  defer atomic.StoreInt32(&adaptor.evaluating, 0)
   
-  // Capture a panic from the evaluation if one occurs and store it in the 
+  // Capture a panic from the evaluation if one occurs and store it (as a 
+  // tk.ExecutionError describing the phase in which it occurred) in the 
   // `err` return parameter. 
   //
+  phase := parsePhase
   defer func() {
 		if r := recover(); r != nil {
-			var ok bool
-			if err, ok = r.(error); !ok {
-        rtnData = tk.Data{}
-				err = errors.New(fmt.Sprint(r))
-			}
+      rtnData = tk.Data{}
+      err = newExecutionError(phase, r)
 		}
 	}()
 
@@ -188,9 +255,11 @@ func (adaptor *GoAdaptor) EvaluateCode(
 	}
 
 	// Compile the ast.
+  phase = compilePhase
 	compiledSrc := ir.CompileAst(srcAst)
 
 	// Evaluate the code.
+  phase = runPhase
 	results, types := ir.RunExpr(compiledSrc)
     
   // If the source ends with an expression, then the result of the execution 
@@ -221,6 +290,43 @@ func (adaptor *GoAdaptor) EvaluateCode(
 	return tk.Data{}, nil
 }
 
+// Interrupt the currently running evaluation (if any) using gomacro's own 
+// (Ctrl-C) interrupt mechanism. The interrupted evaluation panics, which 
+// EvaluateCode returns as an error. 
+//
+// Since gomacro only checks for interrupts between interpreted 
+// statements, a blocking call into compiled code (such as time.Sleep) is 
+// only interrupted once it returns. 
+//
+func (adaptor *GoAdaptor) Interrupt() {
+  // gomacro remembers an interrupt until the next statement runs, so only 
+  // interrupt while evaluating (otherwise the NEXT evaluation would be 
+  // interrupted) 
+  //
+  adaptor.irLock.Lock()
+  defer adaptor.irLock.Unlock()
+
+  if atomic.LoadInt32(&adaptor.evaluating) != 0 {
+    adaptor.ir.Interrupt(os.Interrupt)
+  }
+}
+
+// Restart the adaptor by replacing the gomacro interpreter with a fresh 
+// one (so forgetting all of the user's declarations and imports). 
+//
+func (adaptor *GoAdaptor) Restart() error {
+  fresh := NewGoAdaptor()
+
+  adaptor.irLock.Lock()
+  defer adaptor.irLock.Unlock()
+
+  adaptor.ir      = fresh.ir
+  adaptor.display = fresh.display
+  adaptor.render  = fresh.render
+  adaptor.receipt = nil
+  return nil
+}
+
 // find and execute special commands in code, remove them from returned 
 // string 
 //
@@ -234,10 +340,16 @@ func (adaptor *GoAdaptor) EvaluateRemoveSpecialCommands(
 	stop := false
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
-		if len(line) != 0 {
+		if line == "exit" || line == "quit" {
+			// ask the front-end to exit (and shutdown the kernel)
+			if err := askExit(adaptor.receipt); err != nil {
+				fmt.Fprintf(outerr.Err, "%s: %v\n", line, err)
+			}
+			lines[i] = ""
+		} else if len(line) != 0 {
 			switch line[0] {
 			case '%':
-				evalSpecialCommand(ir, outerr, line)
+				evalSpecialCommand(ir, adaptor.receipt, outerr, line)
 				lines[i] = ""
 			case '$':
 				evalShellCommand(ir, outerr, line)
@@ -257,11 +369,19 @@ func (adaptor *GoAdaptor) EvaluateRemoveSpecialCommands(
 
 // execute special command. line must start with '%'
 //
-func evalSpecialCommand(ir *gomacro.Interp, outerr tk.OutErr, line string) {
+func evalSpecialCommand(
+  ir      *gomacro.Interp,
+  receipt *tk.MsgReceipt,
+  outerr  tk.OutErr,
+  line    string,
+) {
 	const help string = `
 available special commands (%):
 %help
 %go111module {on|off}
+%load file
+
+ask the front-end to exit (and shutdown the kernel): exit (or quit)
 
 execute shell commands ($): $command [args...]
 example:
@@ -284,13 +404,48 @@ $ls -l
 		} else {
 			panic(fmt.Errorf("special command %s: expecting a single argument 'on' or 'off', found: %q", cmd, arg))
 		}
+	case "%load":
+		if err := loadFile(receipt, arg); err != nil {
+			fmt.Fprintf(outerr.Err, "special command %s: %v\n", cmd, err)
+		}
 	case "%help":
-		fmt.Fprint(outerr.Out, help)
+		// show the help in the front-end's pager (if it has one)
+		if receipt == nil || receipt.Page(MakeData(tk.MIMETypeText, help), 0) != nil {
+			fmt.Fprint(outerr.Out, help)
+		}
 	default:
 		panic(fmt.Errorf("unknown special command: %q\n%s", line, help))
 	}
 }
 
+// loadFile replaces the current cell with the (commented) `%load` command 
+// followed by the contents of the file, which (as in IPython) can then be 
+// edited and run. 
+//
+func loadFile(receipt *tk.MsgReceipt, path string) error {
+	if path == "" {
+		return errors.New("expecting a file name")
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if receipt == nil {
+		return errors.New("connection with Jupyter not available")
+	}
+	text := fmt.Sprintf("// %%load %s\n%s", path, contents)
+	return receipt.SetNextInput(text, true)
+}
+
+// askExit asks the front-end to exit (and shutdown the kernel). 
+//
+func askExit(receipt *tk.MsgReceipt) error {
+	if receipt == nil {
+		return errors.New("connection with Jupyter not available")
+	}
+	return receipt.AskExit(false)
+}
+
 // execute shell command. line must start with '$'
 func evalShellCommand(ir *gomacro.Interp, outerr tk.OutErr, line string) {
 	args := strings.Fields(line[1:])
@@ -0,0 +1,82 @@
+package goIPyGoMacroAdaptor
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+
+  tk "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
+)
+
+// IsCodeComplete parses the code with the gomacro parser to decide if the
+// code is complete. Code whose parse fails at the end of the input (an
+// unterminated block, raw string or comment) is incomplete, code whose
+// parse fails anywhere else is invalid.
+//
+func (adaptor *GoAdaptor) IsCodeComplete(code string) (status string, indent string) {
+  if strings.TrimSpace(code) == "" {
+    return tk.CodeComplete, ""
+  }
+
+  parseErr := adaptor.tryParse(code)
+  if parseErr == nil {
+    return tk.CodeComplete, ""
+  }
+
+  errMsg := parseErr.Error()
+  if strings.Contains(errMsg, "EOF") ||
+     strings.Contains(errMsg, "not terminated") {
+    return tk.CodeIncomplete, strings.Repeat("    ", unclosedBlockDepth(code))
+  }
+  return tk.CodeInvalid, ""
+}
+
+// tryParse parses (but does not compile or evaluate) the code, returning
+// any panic raised by the parser as an error.
+//
+func (adaptor *GoAdaptor) tryParse(code string) (err error) {
+  defer func() {
+    if r := recover(); r != nil {
+      var ok bool
+      if err, ok = r.(error); !ok {
+        err = fmt.Errorf("%v", r)
+      }
+    }
+  }()
+
+  compiler := adaptor.ir.Comp
+  savedLine := compiler.Line
+  defer func() { compiler.Line = savedLine }()
+
+  compiler.Line = 0
+  compiler.ParseBytes([]byte(code))
+  return nil
+}
+
+// unclosedBlockDepth returns the number of brackets, braces and
+// parentheses left open at the end of the code.
+//
+func unclosedBlockDepth(code string) int {
+  var scan scanner.Scanner
+  src := []byte(code)
+  fileSet := token.NewFileSet()
+  file := fileSet.AddFile("", fileSet.Base(), len(src))
+  scan.Init(file, src, nil, 0)
+
+  depth := 0
+  for {
+    _, tok, _ := scan.Scan()
+    switch tok {
+    case token.EOF:
+      if depth < 0 {
+        return 0
+      }
+      return depth
+    case token.LBRACE, token.LBRACK, token.LPAREN:
+      depth++
+    case token.RBRACE, token.RBRACK, token.RPAREN:
+      depth--
+    }
+  }
+}
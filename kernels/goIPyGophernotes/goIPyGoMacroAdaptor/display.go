@@ -82,6 +82,22 @@ func stubDisplay(tk.Data) error {
 	return errors.New("cannot display: connection with Jupyter not available")
 }
 
+func stubClearOutput(wait bool) error {
+	return errors.New("cannot clear output: connection with Jupyter not available")
+}
+
+// stubStdin is the "Stdin" reader used outside of an execute_request. 
+// Its receipt does not allow stdin, so it always reads io.EOF.
+var stubStdin = tk.NewJupyterStdinReader(&tk.MsgReceipt{})
+
+func stubReadLine(prompt string, password bool) (string, error) {
+	return "", errors.New("cannot read input: connection with Jupyter not available")
+}
+
+func stubNewDisplayHandle() (*tk.DisplayHandle, error) {
+	return nil, errors.New("cannot display: connection with Jupyter not available")
+}
+
 // fill kernel.renderer map used to convert interpreted types
 // to known rendering interfaces
 func (adaptor *GoAdaptor) initRenderers() {
@@ -1,3 +1,21 @@
 // An implementation of Gophernotes using goIPythonKernelToolkit.
 //
+// User input
+//
+// Interpreted code can ask the front-end for input (over the kernel's stdin
+// channel, when the execute_request allows it) only through the injected
+// "Stdin" reader and "ReadLine" function, for example
+//
+//     name, err := ReadLine("name? ", false)
+//
+// or
+//
+//     scanner := bufio.NewScanner(Stdin)
+//
+// The process's os.Stdin (and so fmt.Scan and friends) is NOT routed to the
+// front-end: os.Stdin must be an *os.File, and feeding one (through an
+// os.Pipe) would mean asking the front-end for input before the code had
+// read anything, so it remains the kernel process's own (normally empty)
+// standard input.
+//
 package goIPyGoMacroAdaptor
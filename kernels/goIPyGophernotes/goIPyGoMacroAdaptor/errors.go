@@ -0,0 +1,119 @@
+package goIPyGoMacroAdaptor
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+  tk "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
+
+	"github.com/cosmos72/gomacro/base"
+	"github.com/cosmos72/gomacro/go/scanner"
+)
+
+// The phases of an evaluation, used to name the errors raised in them.
+//
+const (
+  parsePhase   = "parse"
+  compilePhase = "compile"
+  runPhase     = "run"
+)
+
+// newExecutionError describes the value `r`, recovered from a panic in
+// the given phase of an evaluation, as an ExecutionError.
+//
+// Parse errors are reported as a "SyntaxError" (one traceback line per
+// error), compile errors as a "CompileError" and interrupts as an
+// "Interrupt". Any other panic is reported by the kind of value panicked
+// with, together with the (non interpreter) Go stack frames which lead to
+// it.
+//
+// Since the stack is captured here, newExecutionError must be called
+// directly from the deferred function which recovered `r`.
+//
+func newExecutionError(phase string, r interface{}) *tk.ExecutionError {
+  if sig, ok := r.(base.Signal); ok && sig == base.SigInterrupt {
+    return tk.NewExecutionError("Interrupt", "evaluation interrupted", nil)
+  }
+
+  switch errs := r.(type) {
+    case scanner.ErrorList :
+      traceback := make([]string, 0, len(errs))
+      for _, anErr := range errs {
+        traceback = append(traceback, anErr.Error())
+      }
+      msg := ""
+      if 0 < len(errs) {
+        msg = errs[0].Msg
+      }
+      return tk.NewExecutionError("SyntaxError", msg, traceback)
+    case *scanner.Error :
+      return tk.NewExecutionError("SyntaxError", errs.Msg, []string{errs.Error()})
+  }
+
+  msg := strings.TrimSpace(fmt.Sprint(r))
+  switch phase {
+    case parsePhase :
+      return tk.NewExecutionError("SyntaxError", msg, nil)
+    case compilePhase :
+      return tk.NewExecutionError("CompileError", msg, nil)
+  }
+
+  name := "panic"
+  switch r.(type) {
+    case runtime.Error :
+      name = "runtime error"
+    case error :
+      name = "error"
+  }
+  return tk.NewExecutionError(
+    name,
+    strings.TrimPrefix(msg, "runtime error: "),
+    userStackFrames(debug.Stack()),
+  )
+}
+
+// userStackFrames extracts, from a Go stack trace (as returned by
+// debug.Stack), the frames of the (compiled Go) code called by the user's
+// code, skipping those of the Go runtime, reflection, the gomacro
+// interpreter and this adaptor.
+//
+func userStackFrames(stack []byte) []string {
+  frames := []string{}
+  lines := strings.Split(string(stack), "\n")
+  for i := 1; i+1 < len(lines); i += 2 {
+    function := lines[i]
+    location := strings.TrimSpace(lines[i+1])
+    if isInterpreterFrame(function) {
+      continue
+    }
+    if plusIndex := strings.LastIndex(location, " +0x"); 0 <= plusIndex {
+      location = location[:plusIndex]
+    }
+    if parenIndex := strings.LastIndex(function, "("); 0 < parenIndex {
+      function = function[:parenIndex]
+    }
+    frames = append(frames, function+" ("+location+")")
+  }
+  return frames
+}
+
+// isInterpreterFrame returns true if the stack frame's function is part
+// of the Go runtime, reflection, the gomacro interpreter or this adaptor.
+//
+func isInterpreterFrame(function string) bool {
+  for _, prefix := range []string{
+    "runtime.",
+    "runtime/debug.",
+    "reflect.",
+    "panic(",
+    "github.com/cosmos72/gomacro/",
+    "github.com/stephengaito/goIPythonKernelToolkit/",
+  } {
+    if strings.HasPrefix(function, prefix) {
+      return true
+    }
+  }
+  return false
+}
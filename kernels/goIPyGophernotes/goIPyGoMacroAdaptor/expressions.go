@@ -0,0 +1,73 @@
+package goIPyGoMacroAdaptor
+
+import (
+  "fmt"
+  "go/ast"
+
+  tk "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
+
+  "github.com/cosmos72/gomacro/ast2"
+  basereflect "github.com/cosmos72/gomacro/base/reflect"
+)
+
+// EvaluateUserExpressions evaluates each of the (named) user expressions
+// in the gomacro interpreter, after the cell's code has been evaluated.
+// Only (single) Go expressions are evaluated, so that declarations and
+// statements can not change the interpreter's state.
+//
+func (adaptor *GoAdaptor) EvaluateUserExpressions(
+  expressions map[string]string,
+) map[string]tk.ExpressionResult {
+  results := make(map[string]tk.ExpressionResult, len(expressions))
+  for name, expression := range expressions {
+    data, err := adaptor.evaluateUserExpression(expression)
+    results[name] = tk.ExpressionResult{ Data: data, Err: err }
+  }
+  return results
+}
+
+// evaluateUserExpression evaluates a single Go expression and returns its
+// (rendered) value. As in EvaluateCode, a panic in any phase of the
+// evaluation is returned as an ExecutionError.
+//
+func (adaptor *GoAdaptor) evaluateUserExpression(
+  expression string,
+) (rtnData tk.Data, err error) {
+  ir := adaptor.ir
+
+  phase := parsePhase
+  defer func() {
+    if r := recover(); r != nil {
+      rtnData = tk.Data{}
+      err = newExecutionError(phase, r)
+    }
+  }()
+
+  nodes := ir.Comp.ParseBytes([]byte(expression))
+  if len(nodes) != 1 {
+    return tk.Data{}, tk.NewExecutionError(
+      "SyntaxError", fmt.Sprintf("not a single expression: %s", expression), nil,
+    )
+  }
+  if _, isExpr := nodes[0].(ast.Expr); !isExpr {
+    return tk.Data{}, tk.NewExecutionError(
+      "SyntaxError", fmt.Sprintf("not an expression: %s", expression), nil,
+    )
+  }
+
+  phase = compilePhase
+  compiledExpr := ir.CompileAst(ast2.AnyToAst(nodes, "userExpression"))
+
+  phase = runPhase
+  results, types := ir.RunExpr(compiledExpr)
+
+  values := make([]interface{}, len(results))
+  for i, result := range results {
+    values[i] = basereflect.Interface(result)
+  }
+  data := adaptor.autoRenderResults(values, types)
+  if len(data.Data) == 0 {
+    data = MakeData(tk.MIMETypeText, fmt.Sprint(values...))
+  }
+  return data, nil
+}
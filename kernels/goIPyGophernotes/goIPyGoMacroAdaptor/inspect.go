@@ -0,0 +1,191 @@
+package goIPyGoMacroAdaptor
+
+import (
+	"fmt"
+	"strings"
+
+  tk "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
+
+	basereflect "github.com/cosmos72/gomacro/base/reflect"
+	gomacro "github.com/cosmos72/gomacro/fast"
+	"github.com/cosmos72/gomacro/xreflect"
+)
+
+// InspectCode returns the type (or, for functions and methods, the
+// signature) of the identifier under the cursor as currently known to
+// the gomacro interpreter. When the detailLevel is greater than zero, the
+// current value of (top level) variables and constants is also returned.
+//
+func (adaptor *GoAdaptor) InspectCode(
+  code        string,
+  cursorPos   int,
+  detailLevel int,
+) (tk.Data, error) {
+
+  words := identifierChainAt(code, cursorPos)
+  if len(words) == 0 {
+    return tk.Data{}, nil
+  }
+
+  comp := adaptor.ir.Comp
+
+  // resolve the first identifier as either a symbol or a type
+  //
+  var node interface{}
+  if sym := comp.TryResolve(words[0]); sym != nil {
+    node = &sym.Bind
+  } else if typ := comp.TryResolveType(words[0]); typ != nil {
+    node = typ
+  } else {
+    return tk.Data{}, nil
+  }
+
+  // now resolve any remaining `.selector`s one at a time
+  //
+  for _, word := range words[1:] {
+    node = lookupSelector(comp, node, word)
+    if node == nil {
+      return tk.Data{}, nil
+    }
+  }
+
+  name := strings.Join(words, ".")
+  description := describeNode(name, node)
+  if description == "" {
+    return tk.Data{}, nil
+  }
+
+  if 0 < detailLevel && len(words) == 1 {
+    if bind, ok := node.(*gomacro.Bind); ok && bind.Desc.Class() != gomacro.FuncBind {
+      if _, isImport := bind.Value.(*gomacro.Import); !isImport {
+        value := basereflect.Interface(adaptor.ir.ValueOf(name))
+        description = fmt.Sprintf("%s\n\nvalue: %v", description, value)
+      }
+    }
+  }
+
+  return MakeData(tk.MIMETypeText, description), nil
+}
+
+// identifierChainAt returns the (dot separated) sequence of identifiers
+// which ends with the identifier at (or just before) the cursor position.
+//
+func identifierChainAt(code string, cursorPos int) []string {
+  if cursorPos > len(code) {
+    cursorPos = len(code)
+  }
+  if cursorPos < 0 {
+    cursorPos = 0
+  }
+
+  // extend the identifier under the cursor to its end
+  //
+  end := cursorPos
+  for end < len(code) && isIdentifierByte(code[end]) {
+    end++
+  }
+  head := code[:end]
+
+  var words []string
+  for {
+    word := gomacro.TailIdentifier(head)
+    if word == "" {
+      break
+    }
+    words = append([]string{word}, words...)
+    head = strings.TrimSpace(head[:len(head)-len(word)])
+    if !strings.HasSuffix(head, ".") {
+      break
+    }
+    head = strings.TrimSpace(head[:len(head)-1])
+  }
+  return words
+}
+
+// isIdentifierByte returns true if the byte can be part of a Go
+// identifier (non-ASCII letters are treated as identifier bytes).
+//
+func isIdentifierByte(b byte) bool {
+  return b == '_' ||
+    ('a' <= b && b <= 'z') ||
+    ('A' <= b && b <= 'Z') ||
+    ('0' <= b && b <= '9') ||
+    0x80 <= b
+}
+
+// lookupSelector resolves `node.word` where node is either an imported
+// package, a symbol, a type or a struct field. Returns nil if the
+// selector can not be resolved.
+//
+func lookupSelector(
+  comp *gomacro.Comp,
+  node interface{},
+  word string,
+) interface{} {
+  var typ xreflect.Type
+
+  switch obj := node.(type) {
+  case *gomacro.Bind:
+    if imp, ok := obj.Value.(*gomacro.Import); ok && obj.Const() {
+      if bind := imp.Binds[word]; bind != nil {
+        return bind
+      }
+      if importedType := imp.Types[word]; importedType != nil {
+        return importedType
+      }
+      return nil
+    }
+    typ = obj.Type
+  case xreflect.StructField:
+    typ = obj.Type
+  case xreflect.Type:
+    typ = obj
+  default:
+    return nil
+  }
+  if typ == nil {
+    return nil
+  }
+
+  field, fieldOk, method, methodOk, err := comp.TryLookupFieldOrMethod(typ, word)
+  if err != nil {
+    return nil
+  }
+  if fieldOk {
+    return field
+  }
+  if methodOk {
+    return method
+  }
+  return nil
+}
+
+// describeNode returns a short (Go like) description of the resolved
+// node.
+//
+func describeNode(name string, node interface{}) string {
+  switch obj := node.(type) {
+  case *gomacro.Bind:
+    if imp, ok := obj.Value.(*gomacro.Import); ok && obj.Const() {
+      return fmt.Sprintf("package %s // import %q", imp.Name, imp.Path)
+    }
+    switch obj.Desc.Class() {
+    case gomacro.ConstBind:
+      return fmt.Sprintf("const %s %v = %v", name, obj.Type, obj.Value)
+    case gomacro.FuncBind:
+      return fmt.Sprintf("func %s %v", name, obj.Type)
+    default:
+      return fmt.Sprintf("var %s %v", name, obj.Type)
+    }
+  case xreflect.StructField:
+    return fmt.Sprintf("field %s %v", name, obj.Type)
+  case xreflect.Method:
+    return fmt.Sprintf("method %s %v", name, obj.Type)
+  case xreflect.Type:
+    if obj.Name() != "" {
+      return fmt.Sprintf("type %s %v", name, obj.GoType().Underlying())
+    }
+    return fmt.Sprintf("type %s = %v", name, obj)
+  }
+  return ""
+}
@@ -15,6 +15,7 @@ var display = imports.Package{
 	Binds: map[string]r.Value{
 		"Any":                r.ValueOf(Any),
 		"Auto":               r.ValueOf(Auto),
+		"DisplayIDKey":       r.ValueOf(tk.DisplayIDKey),
 		"File":               r.ValueOf(File),
 		"HTML":               r.ValueOf(HTML),
 		"Image":              r.ValueOf(Image),
@@ -41,7 +42,11 @@ var display = imports.Package{
 		"SVG":                r.ValueOf(SVG),
 	},
 	Types: map[string]r.Type{
+		"Comm":           r.TypeOf((*tk.Comm)(nil)).Elem(),
+		"CommHandler":    r.TypeOf((*tk.CommHandler)(nil)).Elem(),
+		"CommManager":    r.TypeOf((*tk.CommManager)(nil)).Elem(),
 		"Data":           r.TypeOf((*tk.Data)(nil)).Elem(),
+		"DisplayHandle":  r.TypeOf((*tk.DisplayHandle)(nil)).Elem(),
 		"HTMLer":         r.TypeOf((*HTMLer)(nil)).Elem(),
 		"JavaScripter":   r.TypeOf((*JavaScripter)(nil)).Elem(),
 		"Image":          r.TypeOf((*image.Image)(nil)).Elem(),
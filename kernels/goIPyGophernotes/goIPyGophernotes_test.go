@@ -28,11 +28,11 @@ const (
 )
 
 var (
-	connectionKey string
-	transport     string
-	ip            string
-	shellPort     int
-	iopubPort     int
+	connectionSigner *tk.MsgSigner
+	transport        string
+	ip               string
+	shellPort        int
+	iopubPort        int
 )
 
 //==============================================================================
@@ -57,7 +57,10 @@ func runTest(m *testing.M) int {
 	}
 
 	// Store the connection parameters globally for use by the test client.
-	connectionKey = connInfo.Key
+	connectionSigner, err = tk.NewMsgSigner(connInfo.SignatureScheme, []byte(connInfo.Key))
+	if err != nil {
+		log.Fatal(err)
+	}
 	transport = connInfo.Transport
 	ip = connInfo.IP
 	shellPort = connInfo.ShellPort
@@ -389,7 +392,7 @@ func (client *testJupyterClient) sendShellRequest(t *testing.T, request tk.Compo
 
 	frames = append(frames, []byte("<IDS|MSG>"))
 
-	reqMsgParts, err := request.ToWireMsg([]byte(connectionKey))
+	reqMsgParts, err := request.ToWireMsg(connectionSigner)
 	if err != nil {
 		t.Fatalf("\t%s request.ToWireMsg: %s", failure, err)
 	}
@@ -413,7 +416,7 @@ func (client *testJupyterClient) recvShellReply(t *testing.T, timeout time.Durat
 			t.Fatalf("\t%s Shell socket RecvMessageBytes: %s", failure, err)
 		}
 
-		msgParsed, _, err := tk.WireMsgToComposedMsg(repMsgParts.Frames, []byte(connectionKey))
+		msgParsed, _, err := tk.WireMsgToComposedMsg(repMsgParts.Frames, connectionSigner)
 		if err != nil {
 			t.Fatalf("\t%s Could not parse wire message: %s", failure, err)
 		}
@@ -446,7 +449,7 @@ func (client *testJupyterClient) recvIOSub(t *testing.T, timeout time.Duration)
 			t.Fatalf("\t%s IOPub socket RecvMessageBytes: %s", failure, err)
 		}
 
-		msgParsed, _, err := tk.WireMsgToComposedMsg(repMsgParts.Frames, []byte(connectionKey))
+		msgParsed, _, err := tk.WireMsgToComposedMsg(repMsgParts.Frames, connectionSigner)
 		if err != nil {
 			t.Fatalf("\t%s Could not parse wire message: %s", failure, err)
 		}
@@ -473,8 +476,12 @@ func (client *testJupyterClient) performJupyterRequest(t *testing.T, request tk.
 	client.sendShellRequest(t, request)
 	reply := client.recvShellReply(t, timeout)
 
-	// Read the expected 'busy' message and ensure it is in fact, a 'busy' message.
+	// Read the expected 'busy' message (skipping the 'iopub_welcome' sent when the client subscribed) and ensure it
+	// is in fact, a 'busy' message.
 	subMsg := client.recvIOSub(t, 1*time.Second)
+	for subMsg.Header.MsgType == "iopub_welcome" {
+		subMsg = client.recvIOSub(t, 1*time.Second)
+	}
 	assertMsgTypeEquals(t, subMsg, "status")
 
 	subData := getMsgContentAsJSONObject(t, subMsg)
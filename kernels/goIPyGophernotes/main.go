@@ -2,7 +2,6 @@ package main
 
 import (
 	"flag"
-	"log"
   
   "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
   "github.com/stephengaito/goIPythonKernelToolkit/kernels/goIPyGophernotes/goIPyGoMacroAdaptor"
@@ -10,14 +9,15 @@ import (
 
 func main() {
 
-	// Parse the connection file.
+	// Parse the (optional) handshake port and the (optional) connection 
+	// file; without a connection file the kernel runs standalone.
+	handshakePort := flag.Int("handshake-port", 0,
+		"report the kernel's (random) ports to the provisioner on this port")
 	flag.Parse()
-	if flag.NArg() < 1 {
-		log.Fatalln("Need a command line argument specifying the connection file.")
-	}
 
   adaptor := goIPyGoMacroAdaptor.NewGoAdaptor()
   kernel  := goIPyKernel.NewIPyKernel(adaptor)
+  kernel.HandshakePort = *handshakePort
   
 	// Run the kernel.
 	kernel.Run(flag.Arg(0))
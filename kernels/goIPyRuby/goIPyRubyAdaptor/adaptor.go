@@ -7,6 +7,8 @@ import (
   //"unsafe"
   "fmt"
   "os"
+  "strings"
+  "sync"
   "time"
   
   tk "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
@@ -30,6 +32,20 @@ type GoAdaptor struct {
   // The Ruby State
   //
   Ruby *RubyState
+
+  // The pipe used to interrupt Ruby (see Interrupt). 
+  //
+  interruptReader *os.File
+  interruptWriter *os.File
+
+  // evaluation numbers the evaluations of EvaluateCode, which is running 
+  // while evaluating is true. Both are only changed (and checked) while 
+  // holding the interruptLock, so that an interrupt is only ever sent 
+  // to the evaluation which is running. 
+  //
+  interruptLock sync.Mutex
+  evaluation    int
+  evaluating    bool
 }
 
 // Create a new adaptor.
@@ -61,11 +77,27 @@ func NewGoAdaptor() *GoAdaptor {
     panic("Could not load IPyRubyData.rb into running Ruby!")
   }
   
+  // now start the Ruby thread which watches for interrupts
+  //
+  interruptReader, interruptWriter, err := os.Pipe()
+  if err != nil {
+    panic("Could not create the IPyRuby interrupt pipe!")
+  }
+  watcherData := rubyState.GoEvalRubyHelperString(
+    "IPyRubyStartInterruptWatcher",
+    fmt.Sprintf("IPyRubyStartInterruptWatcher(%d)", interruptReader.Fd()),
+  )
+  if _, isErr := watcherData.Data["evalue"]; isErr {
+    panic("Could not start the IPyRuby interrupt watcher!")
+  }
+  
   IPyRubyDebugging = false
   
   return &GoAdaptor{
     AdaptorIdFormat: adaptorIdFormat,
     Ruby:            rubyState,
+    interruptReader: interruptReader,
+    interruptWriter: interruptWriter,
   }
 }
 
@@ -100,23 +132,81 @@ func (adaptor *GoAdaptor) GetCodeWordCompletions(
   return 0, 0, make([]string, 0)
 }
 
+// Inspect the Ruby object or method named at cursorPos in the code. 
+//
+// Methods are described by their owner, arity and source_location, any 
+// other objects by their class and (inspected) value. 
+//
+func (adaptor *GoAdaptor) InspectCode(
+  code        string,
+  cursorPos   int,
+  detailLevel int,
+) (tk.Data, error) {
+  helperCode := fmt.Sprintf(
+    "IPyRubyInspect(%s, %d, %d)",
+    rubyStringLiteral(code),
+    cursorPos,
+    detailLevel,
+  )
+  data := adaptor.Ruby.GoEvalRubyHelperString("IPyRubyInspect", helperCode)
+  if execErr := rubyExecutionError(data); execErr != nil {
+    return tk.Data{}, execErr
+  }
+  if text, _ := data.Data[tk.MIMETypeText].(string); text == "" {
+    return tk.Data{}, nil
+  }
+  return data, nil
+}
+
+// Determine if the code is a complete Ruby fragment by asking Ruby to
+// compile (but not run) it. Incomplete code is indented by one more
+// (two space) level than its last line.
+//
+func (adaptor *GoAdaptor) IsCodeComplete(code string) (status string, indent string) {
+  if strings.TrimSpace(code) == "" {
+    return tk.CodeComplete, ""
+  }
+  helperCode := fmt.Sprintf("IPyRubyIsComplete(%s)", rubyStringLiteral(code))
+  data := adaptor.Ruby.GoEvalRubyHelperString("IPyRubyIsComplete", helperCode)
+  status, _ = data.Data[tk.MIMETypeText].(string)
+  switch status {
+    case tk.CodeComplete, tk.CodeInvalid :
+      return status, ""
+    case tk.CodeIncomplete :
+      lines := strings.Split(code, "\n")
+      lastLine := lines[len(lines)-1]
+      indent = lastLine[:len(lastLine)-len(strings.TrimLeft(lastLine, " \t"))]
+      return status, indent + "  "
+  }
+  return tk.CodeUnknown, ""
+}
+
 // Setup the Display callback by recording the msgReceipt information
 // for later use by what ever callback implements the "Display" function. 
 //
-// Not currently implemented for the IPyRuby kernel.
+// The IPyRuby kernel does not (yet) implement a "Display" function, but 
+// uses the msgReceipt to answer $stdin requests (see IPyRubyStdin).
 //
 func (adaptor *GoAdaptor) SetupDisplayCallback(receipt tk.MsgReceipt) {
+  stdinReceipt = &receipt
 }
   
 // Teardown the Display callback by removing the current msgReceipt
 // information and setting things back to what ever default the 
 // implementation uses.
 //
-// Not currently implemented for the IPyRuby kernel.
-//
 func (adaptor *GoAdaptor) TeardownDisplayCallback() {
+  stdinReceipt = nil
 }
   
+// RegisterComms gives the Ruby code (see IPyRubyOpenComm and 
+// IPyRubyRegisterCommTarget) the kernel's CommManager. 
+//
+func (adaptor *GoAdaptor) RegisterComms(comms *tk.CommManager) {
+  rubyComms      = comms
+  rubyCommsState = adaptor.Ruby
+}
+
 // Evaluate (and remove) any implmenation specific special commands BEFORE 
 // the code gets evaluated by the interpreter. The `outErr` variable 
 // contains the stdOut and stdErr which can be used to capture the stdOut 
@@ -131,8 +221,97 @@ func (adaptor *GoAdaptor) EvaluateRemoveSpecialCommands(
   return code
 }
 
+// Interrupt the currently running evaluation (if any) by raising an 
+// Interrupt exception in Ruby's main thread. 
+//
+// Ruby code can not be interrupted directly from Go, so we wake up the 
+// IPyRubyStartInterruptWatcher thread (by writing the evaluation's 
+// number to the interrupt pipe) which then raises the Interrupt, if that 
+// evaluation is still running. 
+//
+func (adaptor *GoAdaptor) Interrupt() {
+  adaptor.interruptLock.Lock()
+  defer adaptor.interruptLock.Unlock()
+
+  if adaptor.evaluating {
+    fmt.Fprintf(adaptor.interruptWriter, "%d\n", adaptor.evaluation)
+  }
+}
+
+// startEvaluation numbers the next evaluation (telling Ruby its number) 
+// and marks it as running, after discarding any interrupts of earlier 
+// evaluations still in the interrupt pipe. 
+//
+func (adaptor *GoAdaptor) startEvaluation() {
+  adaptor.interruptLock.Lock()
+  adaptor.drainInterrupts()
+  adaptor.evaluation++
+  evaluation := adaptor.evaluation
+  adaptor.interruptLock.Unlock()
+
+  adaptor.Ruby.GoEvalRubyHelperString(
+    "IPyRubyStartEvaluation",
+    fmt.Sprintf("IPyRubyStartEvaluation(%d)", evaluation),
+  )
+
+  adaptor.interruptLock.Lock()
+  adaptor.evaluating = true
+  adaptor.interruptLock.Unlock()
+}
+
+// endEvaluation marks the evaluation as finished, discarding any of its 
+// interrupts which Ruby has not yet read. 
+//
+func (adaptor *GoAdaptor) endEvaluation() {
+  adaptor.interruptLock.Lock()
+  defer adaptor.interruptLock.Unlock()
+
+  adaptor.evaluating = false
+  adaptor.drainInterrupts()
+}
+
+// drainInterrupts discards anything waiting (at most a millisecond) in 
+// the interrupt pipe. 
+//
+func (adaptor *GoAdaptor) drainInterrupts() {
+  buffer := make([]byte, 64)
+  adaptor.interruptReader.SetReadDeadline(time.Now().Add(time.Millisecond))
+  for {
+    if _, err := adaptor.interruptReader.Read(buffer); err != nil {
+      return
+    }
+  }
+}
+
+// Restart the adaptor by giving the user's code a new top level binding 
+// (so forgetting all of its local variables). 
+//
+// Since Ruby can only be started once per process, any methods, classes 
+// or global variables defined by the user's code remain defined. 
+//
+func (adaptor *GoAdaptor) Restart() error {
+  data := adaptor.Ruby.GoEvalRubyHelperString("IPyRubyRestart", "IPyRubyRestart()")
+  if execErr := rubyExecutionError(data); execErr != nil {
+    return execErr
+  }
+  return nil
+}
+
+// Shutdown the adaptor by stopping Ruby (and hence its interrupt 
+// watcher thread) and closing the interrupt pipe. 
+//
+func (adaptor *GoAdaptor) Shutdown() {
+  adaptor.Ruby.DeleteRubyState()
+  adaptor.interruptWriter.Close()
+  adaptor.interruptReader.Close()
+}
+
 // Evaluate the code and return the results as a Data object.
 //
+// Any Ruby exception raised by the code is returned as a 
+// tk.ExecutionError (rather than as an error shaped Data object) so that 
+// the kernel reports it as an `error` and the cell as failed. 
+//
 func (adaptor *GoAdaptor) EvaluateCode(
   execCounter int,
   execSubCounter int,
@@ -140,7 +319,70 @@ func (adaptor *GoAdaptor) EvaluateCode(
 ) (rtnData tk.Data, err error) {
   adaptorIdStr :=
     fmt.Sprintf(adaptor.AdaptorIdFormat, execCounter, execSubCounter)
+
+  adaptor.startEvaluation()
+  defer adaptor.endEvaluation()
   
   dataObj := adaptor.Ruby.GoEvalRubyString(adaptorIdStr, code)
+  if execErr := rubyExecutionError(dataObj); execErr != nil {
+    return tk.Data{}, execErr
+  }
   return dataObj, nil
 }
+
+// Evaluate each of the (named) user expressions, after the cell's code 
+// has been evaluated, in the binding of the user's code. 
+//
+// As in EvaluateCode, any Ruby exception raised by an expression is 
+// returned as that expression's tk.ExecutionError. Empty expressions are 
+// rejected (with a SyntaxError) without being evaluated. 
+//
+func (adaptor *GoAdaptor) EvaluateUserExpressions(
+  expressions map[string]string,
+) map[string]tk.ExpressionResult {
+  results := make(map[string]tk.ExpressionResult, len(expressions))
+  for name, expression := range expressions {
+    if strings.TrimSpace(expression) == "" {
+      results[name] = tk.ExpressionResult{
+        Err: tk.NewExecutionError("SyntaxError", "empty expression", nil),
+      }
+      continue
+    }
+    data := adaptor.Ruby.GoEvalRubyExpressionString(
+      fmt.Sprintf("user_expressions[%s]", name),
+      expression,
+    )
+    if execErr := rubyExecutionError(data); execErr != nil {
+      results[name] = tk.ExpressionResult{ Err: execErr }
+    } else {
+      results[name] = tk.ExpressionResult{ Data: data }
+    }
+  }
+  return results
+}
+
+// Return a (single quoted) Ruby string literal containing `aStr`. 
+//
+// Inside single quotes Ruby only interprets the `\\` and `\'` escapes, 
+// so no interpolation of the string's contents can take place. 
+//
+func rubyStringLiteral(aStr string) string {
+  return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(aStr) + "'"
+}
+
+// Return the tk.ExecutionError described by an (error) Data object, as 
+// made by MakeExceptionData (or the ANSI-C evalRubyString functions), or 
+// nil if the Data object does not describe an error. 
+//
+func rubyExecutionError(data tk.Data) *tk.ExecutionError {
+  evalue, isErr := data.Data["evalue"].(string)
+  if !isErr {
+    return nil
+  }
+  ename, _     := data.Data["ename"].(string)
+  traceback, _ := data.Data["traceback"].([]string)
+  if ename == "" {
+    ename = "ERROR"
+  }
+  return tk.NewExecutionError(ename, evalue, traceback)
+}
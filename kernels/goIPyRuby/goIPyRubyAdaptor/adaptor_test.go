@@ -0,0 +1,66 @@
+package goIPyRubyAdaptor
+
+import (
+  "testing"
+   "github.com/stretchr/testify/assert"
+   tk "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
+)
+
+// assertions: https://godoc.org/github.com/stretchr/testify/assert
+
+func TestRubyExecutionError(t *testing.T) {
+  okData := tk.Data{
+    Data: tk.MIMEMap{ tk.MIMETypeText: "42" },
+  }
+  assert.Nil(t, rubyExecutionError(okData), "Should not be an error")
+
+  errData := tk.Data{
+    Data: tk.MIMEMap{
+      "ename":     "NameError",
+      "evalue":    "undefined local variable or method `x'",
+      "traceback": []string{ "(cell):1:in `<main>'" },
+      "status":    "error",
+    },
+  }
+  execErr := rubyExecutionError(errData)
+  assert.NotNil(t, execErr, "Should be an error")
+  assert.Equal(t, "NameError", execErr.Name)
+  assert.Equal(t, "undefined local variable or method `x'", execErr.Value)
+  assert.Equal(t, []string{ "(cell):1:in `<main>'" }, execErr.Traceback)
+}
+
+func TestEvaluateCodeReturnsExecutionError(t *testing.T) {
+  adaptor := NewGoAdaptor()
+
+  data, err := adaptor.EvaluateCode(1, 0, "raise ArgumentError, 'bad'")
+  assert.Empty(t, data.Data, "Should not return any data")
+  execErr := tk.AsExecutionError(err)
+  assert.Equal(t, "ArgumentError", execErr.Name)
+  assert.Equal(t, "bad", execErr.Value)
+
+  data, err = adaptor.EvaluateCode(2, 0, "'Hello'")
+  assert.NoError(t, err, "Should not return an error")
+  assert.Equal(t, "Hello", data.Data[tk.MIMETypeText])
+}
+
+func TestEvaluateUserExpressions(t *testing.T) {
+  adaptor := NewGoAdaptor()
+
+  _, err := adaptor.EvaluateCode(1, 0, "x = 21")
+  assert.NoError(t, err, "Should not return an error")
+
+  results := adaptor.EvaluateUserExpressions(map[string]string{
+    "double":    "x * 2",
+    "undefined": "y",
+    "empty":     " ",
+  })
+  assert.NoError(t, results["double"].Err, "Should not return an error")
+  assert.Equal(t, "42", results["double"].Data.Data[tk.MIMETypeText])
+
+  execErr := tk.AsExecutionError(results["undefined"].Err)
+  assert.Equal(t, "NameError", execErr.Name)
+
+  // empty expressions are rejected without being evaluated
+  execErr = tk.AsExecutionError(results["empty"].Err)
+  assert.Equal(t, "SyntaxError", execErr.Name)
+}
@@ -3,6 +3,7 @@
 require 'json'
 require 'pp'
 require 'base64'
+require 'securerandom'
 
 # The following are the "standard" "MIMETypes" for IPython Data
 #
@@ -23,6 +24,10 @@ MIMETypeEName      = "ename"
 MIMETypeEValue     = "evalue"
 MIMETypeETraceback = "traceback"
 MIMETypeEStatus    = "status"
+#
+# The following is the Transient key of a display's (updatable) id
+#
+DisplayIDKey       = "display_id"
 
 # The following are the "standard" "MIMETypes" for IPython Data
 #
@@ -63,7 +68,7 @@ def IsIPyRubyData(aValue)
   return false unless aValue.has_key?('Transient')
   return false unless IsIPyRubyMIMEMap(aValue['Data'])
   return false unless IsIPyRubyMIMEMap(aValue['Metadata'])
-  return false unless IsIPyRubyMIMEMap(aValue['Transient'])
+  return false unless aValue['Transient'].is_a?(Hash)
   return true
 end
 
@@ -210,12 +215,17 @@ def Convert2Data(origValue)
       end
     end
   end
-  #
-  # At the moment there is NO example of the use of the 'Transient' data-key 
-  # in gophernotes and/or the IPython documentation I can find... 
-  #
-  # SO we quitely ignore 'Transient' data.
-  #
+  origValue['Transient'].each_pair do | aTransientKey, aValue |
+    #
+    # Transient data (for example the DisplayIDKey) is a hash of key-value 
+    # pairs which are sent to the front-end but never saved in a notebook.
+    #
+    # We simply ignore any transient data which has no value.
+    #
+    unless aValue.nil? then
+      IPyRubyData_AddTransient(dataObj, aTransientKey.to_s, aValue.to_s)
+    end
+  end
 
   return dataObj
 end
@@ -236,45 +246,473 @@ def MakeLastErrorData(err, errMsg)
   return dataObj
 end
 
-def IPyRubyEval(aString)
+# Make a (Jupyter execution error) Data object describing the exception 
+# raised by the user's code: its class name, its message and those 
+# frames of its backtrace which are not part of the IPyRuby kernel itself. 
+#
+def MakeExceptionData(exception)
+  dataObj = IPyRubyData_New([exception, "exceptionData"])
+  IPyRubyData_AddData(dataObj, "ename", exception.class.name)
+  IPyRubyData_AddData(dataObj, "evalue", exception.message)
+  (exception.backtrace || []).each do | frame |
+    next if frame.start_with?("IPyRubyData.rb:")
+    IPyRubyData_AppendTraceback(dataObj, frame)
+  end
+  IPyRubyData_AddData(dataObj, "status", "error")
+    
+  return dataObj
+end
+
+# Display the value (converted, as is the value of a cell, into a Data 
+# object) as a new output of the current cell. If a displayId is given, 
+# the output can later be replaced by IPyRubyUpdateDisplay. 
+#
+# Returns true if the value was displayed. 
+#
+def IPyRubyDisplay(value, displayId = nil)
+  dataObj = Convert2Data(value)
+  unless displayId.nil? then
+    IPyRubyData_AddTransient(dataObj, DisplayIDKey, displayId.to_s)
+  end
+  return IPyRuby_Display(dataObj, false)
+end
+
+# Replace every output displayed with the displayId by the value. 
+#
+# Returns true if the outputs were updated. 
+#
+def IPyRubyUpdateDisplay(value, displayId)
+  dataObj = Convert2Data(value)
+  IPyRubyData_AddTransient(dataObj, DisplayIDKey, displayId.to_s)
+  return IPyRuby_Display(dataObj, true)
+end
+
+# Clear the outputs of the current cell, for example between the frames 
+# of an animation. If wait is true, the outputs are only cleared once the 
+# next output arrives (which avoids flickering). 
+#
+# Returns true if the outputs will be cleared. 
+#
+def IPyRubyClearOutput(wait = false)
+  return IPyRuby_ClearOutput(wait)
+end
+
+# IPyRubyDisplayHandle displays, and then updates in place, the outputs 
+# with its (unique) display_id, for example: 
+#
+#   handle = IPyRubyDisplayHandle.new
+#   handle.display(MakeMarkdownData("0%"))
+#   ...
+#   handle.update(MakeMarkdownData("100%"))
+#
+class IPyRubyDisplayHandle
+
+  attr_reader :display_id
+
+  def initialize(displayId = SecureRandom.uuid)
+    @display_id = displayId.to_s
+  end
+
+  def display(value)
+    return IPyRubyDisplay(value, @display_id)
+  end
+
+  def update(value)
+    return IPyRubyUpdateDisplay(value, @display_id)
+  end
+
+end
+
+# IPyRubyStdin replaces $stdin so that Ruby code (for example `gets` or 
+# `$stdin.readline`) can request input from the Jupyter front-end. Each 
+# line is requested using the (ANSI-C) IPyRuby_ReadLine global function, 
+# which returns nil (end of file) if the front-end does not allow stdin. 
+#
+# Use `IPyRuby_ReadLine(prompt, true)` directly to request a password. 
+#
+class IPyRubyStdin
+
+  def gets(*args)
+    line = IPyRuby_ReadLine("", false)
+    return nil if line.nil?
+    return line + "\n"
+  end
+
+  def readline(*args)
+    line = gets(*args)
+    raise EOFError, "end of file reached" if line.nil?
+    return line
+  end
+
+  def tty?
+    return false
+  end
+  alias_method :isatty, :tty?
+
+end
+
+$stdin = IPyRubyStdin.new
+
+# $IPyRubyEvaluation is the number (given by the Go adaptor, see 
+# IPyRubyStartEvaluation) of the current, or last, evaluation of the 
+# user's code, which is running while $IPyRubyEvaluating is true. Both 
+# are only changed (and checked) while holding the $IPyRubyInterruptLock. 
+#
+$IPyRubyInterruptLock = Mutex.new
+$IPyRubyEvaluation    = 0
+$IPyRubyEvaluating    = false
+
+# Start a (Ruby) thread which raises an Interrupt in the main thread 
+# whenever the number of an evaluation is written (as a line, by the Go 
+# adaptor's Interrupt method) to the pipe whose reading end is the file 
+# descriptor fd. 
+#
+# Interrupts of any other evaluation (one which has already ended), or 
+# which arrive while no IPyRubyEval is running, are ignored. 
+#
+def IPyRubyStartInterruptWatcher(fd)
+  interruptPipe = IO.for_fd(fd, autoclose: false)
+  Thread.new do
+    while line = interruptPipe.gets do
+      evaluation = line.to_i
+      $IPyRubyInterruptLock.synchronize do
+        if $IPyRubyEvaluating && evaluation == $IPyRubyEvaluation
+          Thread.main.raise(Interrupt)
+        end
+      end
+    end
+  end
+  return nil
+end
+
+# Record the number of the next evaluation of the user's code (so that 
+# only its interrupts are raised, see IPyRubyStartInterruptWatcher). 
+#
+def IPyRubyStartEvaluation(evaluation)
+  $IPyRubyInterruptLock.synchronize do
+    $IPyRubyEvaluation = evaluation
+  end
+  return nil
+end
+
+# The binding in which all user code is evaluated. Each binding created 
+# from the TOPLEVEL_BINDING has its own local variables, so a restart 
+# (see IPyRubyRestart) simply creates a new one. 
+#
+$IPyRubyBinding = TOPLEVEL_BINDING.eval("binding")
+
+# IPyRubyComm is the Ruby end of a (custom message) channel with the 
+# Jupyter front-end (for example an ipywidgets widget). 
+#
+# Comms are either opened by Ruby code (see IPyRubyOpenComm) or by the 
+# front-end, using a target registered with IPyRubyRegisterCommTarget. 
+#
+class IPyRubyComm
+
+  attr_reader :comm_id, :target_name
+
+  def initialize(comm_id, target_name)
+    @comm_id     = comm_id
+    @target_name = target_name
+    @msg_handler   = nil
+    @close_handler = nil
+  end
+
+  # Call the block, with this comm, the message's data and its (binary 
+  # String) buffers, for each message the front-end sends to this comm. 
+  #
+  def on_msg(&block)
+    @msg_handler = block
+  end
+
+  # Call the block, with this comm, the data and any buffers, when the 
+  # front-end closes this comm. 
+  #
+  def on_close(&block)
+    @close_handler = block
+  end
 
-  # prepare to capture any errors from the TOPLEVEL_BINDING.eval
+  # Send the data (a Hash) to the front-end. 
   #
-  evalTrace = TracePoint.new(:raise) do | tp |
-    errReport = [ tp.raised_exception.to_s ]
-    strLines  = aString.lines
-    if tp.lineno < strLines.length then 
-      errReport.push "in line: (#{tp.lineno})"
-      errReport.push aString.lines[tp.lineno]
+  def send_msg(data = {})
+    return IPyRuby_CommSend(@comm_id, JSON.generate(data))
+  end
+
+  # Close this comm (sending the data to the front-end). 
+  #
+  def close(data = {})
+    $IPyRubyComms.delete(@comm_id)
+    return IPyRuby_CommClose(@comm_id, JSON.generate(data))
+  end
+
+  def handle_msg(data, buffers = [])
+    @msg_handler.call(self, data, buffers) if @msg_handler
+  end
+
+  def handle_close(data, buffers = [])
+    @close_handler.call(self, data, buffers) if @close_handler
+  end
+end
+
+# The open comms (by comm_id) and the registered comm targets (by name). 
+#
+$IPyRubyComms       = {}
+$IPyRubyCommTargets = {}
+
+# Register the block to be called, with each new IPyRubyComm and the 
+# comm_open's data and buffers, whenever the front-end opens a comm with 
+# the targetName. 
+#
+def IPyRubyRegisterCommTarget(targetName, &block)
+  $IPyRubyCommTargets[targetName] = block
+  return IPyRuby_CommRegisterTarget(targetName)
+end
+
+# Open a comm with the front-end's targetName (sending it the data). 
+#
+def IPyRubyOpenComm(targetName, data = {})
+  commId = IPyRuby_CommOpen(targetName, JSON.generate(data))
+  raise IOError, "could not open a comm with #{targetName}" if commId.nil?
+  return $IPyRubyComms[commId] = IPyRubyComm.new(commId, targetName)
+end
+
+# Dispatch a comm event ("open", "msg" or "close"), with its (JSON 
+# encoded) data and its (binary String) buffers, from the kernel to the 
+# comm's (or target's) handler. 
+#
+def IPyRubyCommDispatch(event, commId, targetName, dataJSON, buffers = [])
+  data = JSON.parse(dataJSON)
+  case event
+  when "open"
+    comm = $IPyRubyComms[commId] = IPyRubyComm.new(commId, targetName)
+    target = $IPyRubyCommTargets[targetName]
+    target.call(comm, data, buffers) if target
+  when "msg"
+    comm = $IPyRubyComms[commId]
+    comm.handle_msg(data, buffers) if comm
+  when "close"
+    comm = $IPyRubyComms.delete(commId)
+    comm.handle_close(data, buffers) if comm
+  end
+  return nil
+end
+
+# Restart the IPyRuby kernel by forgetting all of the user's (top level) 
+# local variables, comms and comm targets. 
+#
+def IPyRubyRestart
+  $IPyRubyBinding     = TOPLEVEL_BINDING.eval("binding")
+  $IPyRubyComms       = {}
+  $IPyRubyCommTargets = {}
+  return nil
+end
+
+# Evaluate the user's code (see IPyRubyEvalCode) while accepting 
+# interrupts. 
+#
+# Interrupts are deferred while $IPyRubyEvaluating is being changed, so 
+# an interrupt raised just as the code finishes is still raised (and 
+# rescued by the ANSI-C code) before IPyRubyEval returns, rather than 
+# in whatever Ruby code runs next. 
+#
+def IPyRubyEval(aString)
+  Thread.handle_interrupt(Interrupt => :never) do
+    $IPyRubyInterruptLock.synchronize { $IPyRubyEvaluating = true }
+    begin
+      Thread.handle_interrupt(Interrupt => :immediate) do
+        IPyRubyEvalCode(aString, "(cell)")
+      end
+    ensure
+      $IPyRubyInterruptLock.synchronize { $IPyRubyEvaluating = false }
     end
-    errorData = MakeLastErrorData(
-      errReport.join("\n"),
-      "IPyRuby kernel evaluation of Ruby code FAILED"
-    )
-    evalTrace.disable
-    return errorData
   end
-  evalTrace.enable
-  evalResult = TOPLEVEL_BINDING.eval(aString)
-  evalTrace.disable
+end
+
+# Evaluate a (user_expressions) expression in the binding of the user's 
+# code (see IPyRubyEvalCode). 
+#
+# Unlike IPyRubyEval, the expression is not evaluated as a cell, so it 
+# can not be interrupted. 
+#
+def IPyRubyExpressionEval(aString)
+  return IPyRubyEvalCode(aString, "(expression)")
+end
+
+# Evaluate the user's code in the $IPyRubyBinding and convert its result 
+# (or the exception it raised) into a goIPyRuby Data object. 
+#
+def IPyRubyEvalCode(aString, fileName)
+  # evaluate the user's code (as the fileName, for example "(cell)", so 
+  # that its backtrace frames can be told apart from those of this 
+  # kernel) capturing any exception (including a SyntaxError) it 
+  # raises... 
+  #
+  begin
+    evalResult = $IPyRubyBinding.eval(aString, fileName, 1)
+  rescue Exception => exception
+    return MakeExceptionData(exception)
+  end
 
-  # prepare to capture any errors from the Convert2Data
+  # ... or raised while converting its result
   #
-  convertTrace = TracePoint.new(:raise) do | tp |
-    errReport = [
-      tp.raised_exception.to_s,
-      caller.shift
-    ]
-    errorData = MakeLastErrorData(
-      errReport.join("\n"),
-      "Convert2Data FAILED"
-    )
-    convertTrace.disable
-    return errorData
+  begin
+    convertedResult = Convert2Data(evalResult)
+  rescue Exception => exception
+    return MakeExceptionData(exception)
   end
-  convertTrace.enable
-  convertedResult = Convert2Data(evalResult)
-  convertTrace.disable
-  
+
   return convertedResult
 end
+
+# Evaluate (kernel internal) helper code, such as IPyRubyInspect, and 
+# convert its result into a goIPyRuby Data object. 
+#
+# Unlike IPyRubyEval, any exceptions are NOT captured here (they are 
+# reported by the ANSI-C code), so helpers may freely raise and rescue 
+# their own exceptions. 
+#
+def IPyRubyHelperEval(aString)
+  return Convert2Data(TOPLEVEL_BINDING.eval(aString))
+end
+
+# Return the name (a (possibly) `.` or `::` separated sequence of Ruby 
+# identifiers) found at the cursorPos in the code. 
+#
+def IPyRubyNameAt(code, cursorPos)
+  head = code[0, cursorPos].to_s[/[$@]*[\w:.]*[?!]?\z/].to_s
+  tail = code[cursorPos..-1].to_s[/\A\w*[?!]?/].to_s
+  return (head + tail).sub(/\A[.:]+/, '').sub(/[.:]+\z/, '')
+end
+
+# Describe a Ruby Method (its owner, arity and source_location).
+#
+def IPyRubyDescribeMethod(name, aMethod, detailLevel)
+  location = aMethod.source_location
+  location = if location.nil? then "(native method)"
+             else                  location.join(':')
+             end
+  description = [
+    "#{name}",
+    "  owner:           #{aMethod.owner}",
+    "  arity:           #{aMethod.arity}",
+    "  source_location: #{location}",
+  ]
+  if 0 < detailLevel then
+    description.push "  parameters:      #{aMethod.parameters.inspect}"
+  end
+  return description.join("\n")
+end
+
+# Describe any other Ruby object (its class and inspected value).
+#
+def IPyRubyDescribeObject(name, anObject, detailLevel)
+  description = [
+    "#{name}",
+    "  class: #{anObject.class}",
+    "  value: #{anObject.inspect}",
+  ]
+  if 0 < detailLevel then
+    description.push "  methods: #{anObject.public_methods(false).sort.inspect}"
+  end
+  return description.join("\n")
+end
+
+# Bound to an object, Kernel#method looks up one of the object's methods 
+# (without calling it), even if the object's class overrides #method. 
+#
+IPyRubyKernelMethod = Kernel.instance_method(:method)
+
+# Return the Ruby object named by a single name (a local variable, a 
+# constant, a global or an instance variable of the top level object), 
+# or nil if nothing of that name exists. 
+#
+# Names are only ever looked up (never evaluated), so inspecting code 
+# can not run any of it. 
+#
+def IPyRubyResolve(name)
+  case name
+  when /\A[a-z_]\w*\z/
+    return nil unless $IPyRubyBinding.local_variable_defined?(name.to_sym)
+    return $IPyRubyBinding.local_variable_get(name.to_sym)
+  when /\A(::)?[A-Z]\w*(::[A-Z]\w*)*\z/
+    return Object.const_get(name.sub(/\A::/, '')) rescue nil
+  when /\A\$\w+\z/
+    # only the (validated) name of an existing global is evaluated, which 
+    # simply reads its value 
+    #
+    return nil unless global_variables.include?(name.to_sym)
+    return TOPLEVEL_BINDING.eval(name)
+  when /\A@\w+\z/
+    aReceiver = $IPyRubyBinding.receiver
+    return nil unless aReceiver.instance_variable_defined?(name)
+    return aReceiver.instance_variable_get(name)
+  end
+  return nil
+end
+
+# Return the (uncalled) Method named methodName of the object, or nil if 
+# the object has no such method. 
+#
+def IPyRubyMethodOf(anObject, methodName)
+  return nil unless methodName =~ /\A\w+[?!]?\z/
+  return IPyRubyKernelMethod.bind(anObject).call(methodName) rescue nil
+end
+
+# Return an IPyRubyData text description of the Ruby object or method 
+# named at the cursorPos in the code, or nil if nothing can be found. 
+#
+# (Note that Jupyter's cursorPos counts unicode characters, as does 
+# Ruby's String#[]).
+#
+def IPyRubyInspect(code, cursorPos, detailLevel)
+  name = IPyRubyNameAt(code, cursorPos)
+  return nil if name.empty?
+
+  receiverName, dot, methodName = name.rpartition('.')
+  if dot.empty? then
+    # a single name: a local variable, a constant, a global or instance 
+    # variable or a method of the top level object
+    #
+    anObject = IPyRubyResolve(name)
+    if !anObject.nil? then
+      return MakeTextData(IPyRubyDescribeObject(name, anObject, detailLevel))
+    end
+    aMethod = IPyRubyMethodOf($IPyRubyBinding.receiver, name)
+    return nil if aMethod.nil?
+    return MakeTextData(IPyRubyDescribeMethod(name, aMethod, detailLevel))
+  end
+
+  # a method of a (single named) receiver
+  #
+  return nil if receiverName.empty? || methodName.empty?
+  aReceiver = case receiverName
+              when 'nil'   then nil
+              when 'true'  then true
+              when 'false' then false
+              when 'self'  then $IPyRubyBinding.receiver
+              else              IPyRubyResolve(receiverName)
+              end
+  return nil if aReceiver.nil? && receiverName != 'nil'
+  aMethod = IPyRubyMethodOf(aReceiver, methodName)
+  return nil if aMethod.nil?
+  return MakeTextData(IPyRubyDescribeMethod(name, aMethod, detailLevel))
+end
+
+# Return an IPyRubyData text object containing one of "complete", 
+# "incomplete" or "invalid" depending upon whether or not the code 
+# compiles. Code which only fails to compile because it ends too soon 
+# (an unclosed block, string or bracket) is "incomplete". 
+#
+def IPyRubyIsComplete(code)
+  oldVerbose = $VERBOSE
+  $VERBOSE   = nil
+  RubyVM::InstructionSequence.compile(code)
+  return MakeTextData("complete")
+rescue SyntaxError => err
+  if err.message =~ /unterminated|end-of-input|\$end|expected an `end`/ then
+    return MakeTextData("incomplete")
+  end
+  return MakeTextData("invalid")
+ensure
+  $VERBOSE = oldVerbose
+end
@@ -75,6 +75,12 @@ class TestIPyRubyData < Test::Unit::TestCase
       "Transient" => {}
      }),
       "We have a IPyRubyData")
+     assert(IsIPyRubyData({
+      "Data" => {"text/plain" => "Hello world!"},
+      "Metadata" => {},
+      "Transient" => {DisplayIDKey => "aDisplayId"}
+     }),
+      "We have a IPyRubyData with a display_id")
   end
  
 #  def test_MakeLastErrorData
@@ -265,4 +271,75 @@ class TestIPyRubyData < Test::Unit::TestCase
     assert(someData['Data'][MIMETypeText].include?("def IPyRubyEval"),
       "should have correct MIMETypeText value")
   end
+
+  def test_IPyRubyInspectDoesNotEvaluate
+    $IPyRubyInspectProbed = false
+    probe = Class.new do
+      def probe
+        $IPyRubyInspectProbed = true
+      end
+      def method(*args)
+        $IPyRubyInspectProbed = true
+      end
+    end
+    $IPyRubyBinding.local_variable_set(:aProbe, probe.new)
+
+    someData = IPyRubyInspect("aProbe.probe", 8, 0)
+    assert(IsIPyRubyData(someData), "should have been Data")
+    assert(someData['Data'][MIMETypeText].include?("arity:           0"),
+      "should have described the probe method")
+    assert(!$IPyRubyInspectProbed, "should not have called any probe methods")
+
+    assert(IPyRubyInspect("aProbe.probe.probe", 14, 0).nil?,
+      "should not resolve a method call's result")
+    assert(IPyRubyInspect("(aProbe.probe).probe", 17, 0).nil?,
+      "should not evaluate a parenthesised expression")
+    assert(!$IPyRubyInspectProbed, "should not have called any probe methods")
+
+    someData = IPyRubyInspect("$IPyRubyInspectProbed", 3, 0)
+    assert(someData['Data'][MIMETypeText].include?("class: FalseClass"),
+      "should have described the global")
+  end
+
+  def test_IPyRubyInterruptWatcherIgnoresStaleInterrupts
+    reader, writer = IO.pipe
+    IPyRubyStartInterruptWatcher(reader.fileno)
+    IPyRubyStartEvaluation(2)
+    $IPyRubyInterruptLock.synchronize { $IPyRubyEvaluating = true }
+    stage = nil
+    begin
+      # an interrupt of the previous evaluation is ignored...
+      stage = 1
+      writer.puts(1)
+      sleep 0.5
+      # ... but one of the current evaluation is raised
+      stage = 2
+      writer.puts(2)
+      sleep 5
+      stage = 3
+    rescue Interrupt
+    ensure
+      $IPyRubyInterruptLock.synchronize { $IPyRubyEvaluating = false }
+    end
+    assert_equal(2, stage, "the wrong evaluation was interrupted")
+  end
+
+  def test_IPyRubyCommDispatchPassesBuffers
+    opened   = nil
+    received = nil
+    $IPyRubyCommTargets['buffers'] = proc do |comm, data, buffers|
+      opened = buffers
+      comm.on_msg { |aComm, someData, someBuffers| received = someBuffers }
+    end
+
+    IPyRubyCommDispatch("open", "buffers-comm", "buffers", "{}", ["open"])
+    IPyRubyCommDispatch("msg", "buffers-comm", "buffers", "{}",
+      ["\x00msg\xff".b])
+    assert(opened == ["open"], "should have passed the comm_open buffers")
+    assert(received == ["\x00msg\xff".b],
+      "should have passed the comm_msg buffers")
+  ensure
+    $IPyRubyCommTargets.delete('buffers')
+    $IPyRubyComms.delete('buffers-comm')
+  end
 end
@@ -212,44 +212,115 @@ var _escData = map[string]*_escFile{
 	"/lib/IPyRubyData.rb": {
 		name:    "IPyRubyData.rb",
 		local:   "lib/IPyRubyData.rb",
-		size:    7171,
-		modtime: 1589978722,
+		size:    21291,
+		modtime: 1792314537,
 		compressed: `
-H4sIAAAAAAAC/7wYXW/bOPK5+hVzcrG1t66KOyz2odig126c1LeJbcRB7iEXBLQ0tphIpJak4xi3998P
-Q4kSZctp0uttgcbicL5nODNkDy5TrkGtF1vQseKFAZ4XGeYojAaTIpyzezxmhkGOJpVJECj8fc0Vwps7
-LcWbZlkU3mLBNP7805sgIAEIS5llcsPFCphCyzbUhomEqSSE8Hx8PrrcFqhDWEoF49nWpFIASQ16gdv9
-cnl+BuW/IwgNPpr3qcmzsEb4B3tg89KGIwhZUWQ8ZoZL8f6OPbDSOg97Njpt2PGcrfD9XYErD2M+nTQY
-LX5aigbvjBl8bCuWEajBOGfqPpEb4WHkFahBmk2cPo1ChfD0mR2fQLc+RbJs0OZX+3z0w+rto++rS3w0
-O84sMsZFGPQ6Q8ZogQmEOc/RNLFCpaQChYVUhouVF67RhOVYS0DBcmzEj65YtsZ684FW3u6lYjEuWHxv
-lXMLD2FumFnrilzbRfg9cm08216sF1tCOmfFb7jVcATXwSs/B4fNssk4HzgbnfrL+XTiLW2yeGuXGh5o
-NvHpZ8cn3mp+5e9RFIfBTfA/hqxt9Yj2a9MBWgEd+gAbxBakDlwLWkbLKtoW9SnLnKB9zw/hgF430TJj
-xqAIggSXMNZtvD6zeg0CAIVmrQQsWaYR1iJDraHcjbi+ZR/7X5hODyFWfKJ70vAddGo+iDIUK5PCL/DX
-ho1RawxQJDv6UY59L+UqxJTp23vcfuy/IeZvno19joYlL6K4VExojsIcJDkQh+tStZsXk9U6vpy0Udan
-bYeF+toJz2xv69MRoVQdwpJnOGPGOp6+f5XC2F54BONppJAlfR8lZhrBUQcAmxQF+JUgoCJFsmhhZflc
-B7sks+OTmmJ2fPIMgkkjYjbploCZxhqpw94WrkjaPqKSZ2m0zPGLyTPPozW/Vn0EhxkZeasHbXZNyayZ
-NqCnWHu1FtpUvpgeTKShqs8M2A5v+x8stga1rYraSIUJMF3BuNA8QWBAeQRzo2xJhP4m5XEKP8ZM/Ahc
-xNk6QRDrLCvJBlHQa0xykbVqzUanY5L52eLtG/RJJFS2+2VAWj2DIPs8rHnl5mc7VUUoYpngzz/1DyCT
-1F23z6eT7vqz72hqWEB/oxUKVMygo9phantZbbldPdfgqg0SKHwdvq3prf6RNooXb8PXYeOTZnffONdD
-a1Uc4Ckz68YLPkVXyp4zk34HKw+b+TU7/aSmIfD/ms2u6pAys+OTF6WxnVWcJY74K/nbQhscMHrypxxk
-Vz+tWpNvOMZ2cqvtnzz/EO/jdpzh+VWj3vzq9KnkpjERKryulCbVa160eIqZnTLBYXax2+kp1LWJoamk
-wJEF7UOiQqEx21sudIGxieJU5oXr7hahnITKaH4tCrUCdkXk5ZcTuu9Rn0Vb/WFN5UmlDeC7E52zln6b
-a015N6ERLhK48bfdQNTeduLhCFp3NDfq7I3HUZXMH2vNnRKV7G9zsBehMCRjHSASPPvYRqi3npJSI+1K
-2nPItW/4jce/E9lZfeOZ20ZsBsjDofAmxTaOF3KL2iTOr1I8oDJ/s8GXiq9cRw0AeoBCrxVCDYfxHJgA
-L2FALu4wNoQdgId4BDZb+bKBWZ/TFXY/49uHMwzp/8AyoGkP9v/V55RwfbWhHPqs9hO5gY1U97DhJrV3
-55WsVIeYZRld7TQYCXHpBDAp1x3Glb7gwkhgHot988m/08UdTdcNk9sJblqeBd9RLgMiZHF6WzCuIJHw
-BzByyW+4HVaXF/jD2ubz/ZQk9ZGdLu6GezRuAm6Ja/LomSJ75V9whMA1MIhllmFsuBQgl5AynWJCX/e4
-fWcfQIAYa4ilUqgLKRK6yRsJFTeS3VUHWiL/iaB5XmRb4CshFQITW8idHmUv5BqENF5WNtxAKtqrmDGr
-ZYeOvky+7Li1wg8/PFmynOMGNrmDMkMrNns+RsNKH9OX7+eO8DqXd4a44lT2Yo9d3XDdWWh+y789m86f
-jD0RucxR2E+F5MvJFPCR5UWG5CnCWOv60yswNtff3ePWHQ5YySJFJaQdaUTyXipL496kEhmvSZR944Mx
-xEzAkoskiiKolZpPYYPw+5obbIK+KzWy6O2qNl3c7U7z2tg3FntEUKkhoFLnejWAoAexXGcJWfaBVq//
-Aq8yZlCb6jkpR63ZCmnr7/Aqk+XDJHnB7hP8Fl5pW/whY9oAXaVhsYUVGk3bEbzKuKDZLF+gauNwYVBR
-k0EFZTU4XDeuPc2HEGa+VaF9DniyIlQvlUMIRxcX04vwGQTl66WVWWdSi6QoUCT101hDWrn3qxKqB06S
-RZZUdf6JcFbsRg8s6zPXcW26FAoL+ywqIWaFWVcVwvLVsFQytyl4OZ2dja5GZ7efx5Pj8eQ0IiOrnKNP
-awwcgf2dSS4Mtc3+B8W4xkF5cE1RHVNU6sK+NsIRXIMpIouV3OJjjAWlifUa3JSTs1FnXKCmll2pHlFe
-aFdsTGHXQsIvNbJ7hbOd0p1jJzUq1jqFkAsgug/Q7/275vGfQdiN3hJ9XePfBK0S4fKKhra9E7TH+E5y
-0Q//JcLBsNoLXWO8RyUws65d1yen7LoyQTj5ND4bHZeqVh3bBSFKuGaLrHzfqfKh1qsuYA06igqbQBeo
-1xmFpTPeTep0yXtJOvkjU5VF1QDx7YlUufBQOjkX09SCKtIpX5Zt7eY7hc63qSNAvn3Pi1GLog5TBcWk
-jlVr/myiOAgOCm0qxQ43WzH+GwAA//8uLynyAxwAAA==
+H4sIAAAAAAAC/708a3PbRpKfw18xobwWENNwcnV1H1jLc/xQYu1akkvyeqtOVkkgMRRhgQAWD8uM7fvt
+1495AiAlJ9lLlW0C09Pd093TrxlkT7xdpbWo2vlG1IsqLRuRrstMrmXe1KJZSXEU38iXcROLtWxWRTIa
+VfJfbVpJsf+hLvJ9+1iWzsM8ruV//afzopaLtpJVnCfFen802gO6UiyLLCtu0/xaxACD1MZ1AyBxlYzF
++Ojw6ODtppT1GAArcfhmAwzkApkZ7Y306Ku3R68F/zcT40Z+ap6smnU2NgB/iz/GZ7w0AIjLMksXcZMW
++ZMPMMKLdqDfHPxq0aXr+Fo++VDKawfi7OTYQnj4QCAW7nUMzPiMZfjKQhzF1U1S3OYOxFq9skBvjjU/
+lqEyd/h58/IXMcxPmSwt2Nm7Pp764/WjT66s3gIPHWGWWZwCO3uDKovxQSZivE7BPKyuZFXB35Usi6oB
+WEddB8fxWhoKMocnS/7gXZy10gx+xCdn9G0VL+Q8XtwQc/rBAThr4qat1fSaHoY4T9myAV1ep2Do4kZu
+RLEUsUjSGta72a9F0JYJGNo8k6FIE0DykocOX/4dgM0C1ITLNBn/GUYNj6ewFRHoKC6BUg1EzkffucY+
+sY/WtN2XYMHuI5ir80hW6TxrG3RegcW5Ty9/cZ7AipwnNJfJ6GL0B23DX/UBjpulC+FZzsR9QdbivTEW
+4r1lsyBGfVLPskwT6kt+IrbwdREtYSM3Mh+NErkUh7UPF8TEVwgcVLJpq1ws46yWos0zWdeCR6O0voyf
+Bq/ierUNUOGJbpDDx2KQ8zDKZH7drMRfxU8WTVO1ciTzpMMf2tifxZwCXMX1JfD3NNhH5Pv3hj6STZx8
+0wyzWbdO2aKHc2bt4punGR4vdjN57vB2MSw6XyEYUX9JM4qqAW4ONNKJWMKrN3FD8/D3iyJvKArPxOFJ
+VMk4CVyQBURYoWfD8+1K5sL1ASN0UUgLH4iWizXsToF9bmbA73tMOLYk4PfgBAnSMkAD6/VgQT6ejNDZ
+0Zy6WMtXENMdiRp8nmcUGjJqiktA6qGzztIgta92oXa8rPBnuWT2xHHRoL+PG0FJBIVYMd80siZ/WDdF
+Be4wrtW7FGwmATcp0PjEWVORMxTB7SpdrMQPizj/AWAWWQtAeZtlPC2MwGGaJWnNElvwcIg0nxNcf0HP
+8gQddsAK8aIFvunjoOXx4HPK5yKZL4oEfgRbgJFqV+wQf4Y9T1/QGKoE/h1dyxySxUbqWR2kFMXMyunp
+vgtWARBfjR+MH5n5xH9UgxrKRzBgZWJH+4vT0dOwol/sWqYJucKdMWSyR7DX/4RVbl/mXet0jRrzzH+r
+NWuvg8zA728yY8pS9Er05Dvs1wMLtyz6+P9lI2v/SWwd/45tTDmbWf/x/TdxH3ZgD0PWZ9iD37uMGxNE
+oeCGTBpZN7jwYRcyyi+FhhxC14kpGK8RYaOoQOzEV/03UQk0G8jb87qUiyZarIp1qeM6AXAgZ23epQXD
+AD3hdP6lifYl6qLw2Z+YWQ5VHBBpN5fTq8V/beXElQlmIFEub91hnQr5w5o8vPXKQJ0f9RLjSBnzU8O5
+ZkLR/n0CdjQ0HuNi9YsoT7OnPoAZ2kXFAHUp9QRy7i78wsE/CKxXfeEs1we0qeN2VThJow/jqJxAreFA
+tvRRVs1/kPKLKr3WERVm7UH+VLfgncx7cXgm4lw4BiOK+QeQEkLDHws4E2StIHLzjmSOxWvf4v3NOR7j
+n5AQYLYn+v+ZfYqwLtuCkz7i/ri4FbdFdSNuUyhnsGq+LhTrkOpmGRZ1oNJCLFgIAJLWA4tjWaQ5QMYO
+iv7yUb4n8w+YXVskl8fy1pOscAWlLSCS8WJ1WcZpJZJCfBExigR2xkRVBOILrc3F+yxJzJYFqpPeHJ0B
+e+SsHd2T5B7/LfREbHXEILIsg4WnRY5dDqipVhC+4BdUVo+pxyIQcQ1wVSXrssgTrOFBggob0h7yAx7J
+f0JgxNbhRqTXeYH1f77BniHzwbEQuMmLxrFKiw2WjWMKWUxcDvDo0gR77der4uHDnS5LCy4k4x6xhSo0
+PRkD9yxj/OXKeUC9WuSDKlaYOBY76EzA1XvB/tszBrfK7HJqxrabhO12kUICasV8irHZS9vN7W+FbDd9
+HShcbC6sU8qIEC3YC+JZVlDTPQbuxbxtRC5hs4o6/ggWl+aAE3Qs50Vxcw/jaXyOmRzwBDgEsePi8PsG
+xnuNBnVlZOEoyxWh1lNXR1Yzvp8GDN36pG6oX0SbXlbVBNteR/V1KCAzXBRtloi2llN8evC9+A4bw3Wj
+WmNrWAhkZDj0MwwV3M1FRdA4vr8U39UUzkQGlAQ2ByCrFNeyqXE4gmlpjtnmeg7i92DANcoKwyYMsH/b
+7gnPHc7BzWfuqsbUFtnp41R7F34cnJ6enI7vMYFbvkTTyN2bUpYgaNPms1OVeO+koLrCSAtXoiLXFnXu
+kTLBaoO/teUGJSY/yUVL6qDpoRtYRCLxNGFOzhN2gvy0kCXBAqIqTmtJCsAh0H21jw43ARtIG/gFsgXD
+xg4nPSsbgH2QwIQC4irgWFYwXqMhIAiun5rgzkZE71rGEB0BBuno8Hcjq1xmOE1my0g4lceBZpItVT+F
+d9iFhkM5uhi+xSrMxIhWH+HrbzERM10JC+faFURWPl++iPOLkJwm+0sSpPKPOea6EEroHRTGIL1LzEGe
+BmOHkaiaT5Wt3MscCZuN6X+qTSpPTQpmtxyopEgmEyxL1fkGj9G5xkJm2URnRWSygIetNsQZ4JflrSja
+pmyN7SxayAbA+eLcSBw6xyOHCZK4Tj9K0D8gQmg1F4pdgc6sEnOJPf4MJENWryTwDzxVkWoFZIh74pRW
+WVOXlPJ+w/stsKZoysSYrRYmDwQEOnF4mwlw/74Be5nzR51v6WpEz+yEjbuDhhszHQ6M52LlKy0qdJea
+b2sr2FM2vYdTFprAwLnRYjVCsOmxXe/cMYUdImVUELYlOAo63erL1FNPT7L3kekfF9pd4sI1GWm9yGRc
+ecsbsN6JcJOduWxuJdQ1nKyQR8XNsMS0NM7TNUVbMvjbOG1oM1UoBpcI+toih3RlgfQxm84XnEYBKvIo
+SnFxVcE2qXUzKP5YpEktllm6AJeM5eh9FJZiz0hqWl2dkQhOCDYgjmfaonqy7IIaMfpb6hVEncxYGCZC
+FIVAZmw22PISZKW+VAARmSdGp6DN03+BooQ9IfXUMOV1C0joiNhskAcqiS1UpJAFvd7r+Me/jMOQQKMo
+cqcwywMzfvpRzRlx8B2ij3le3DTVJWZP4NSmdjU4hFpI87RJ4yz9TQauCzqj+w6ndN8hats0Yaf+s0WA
+nQPP+G1qiXgTdxPyZF+bnV3qYA59TEoCWxENbvohdL6xnDVQJmofX4sHNT3XBTdOuXCH/MavNK4wRb3C
+Yg8QXfEcOtvCfPUqpPiBd0YwG05z3EFQT6zJynQKZgqMSBxgRAdElOziNRaeKTG51llY8Oz47PDxi9Bs
+glOg9honXGfFPM7Ess0XnMqg/dI2rdRuhHgAGUVOxTIeV4V6a9oiJymkKmrxuFvwgti2/wFZ21WXalDC
+evAkidzYFai5giAMngRCs154DDlcXd8WFe91zzxJ6lqzKMzgh7i6VvUJyWHWWyk1XoxPMOrH1cF6cJJu
+sZkxwvRIjN/nY9+YtK4GyHa5oZRXHJz8QjUDJX5GkohnsZLJeCcHPumm2XgQtCITZWELxvUlX1IS07SG
+bQvhZUqT2HKVhc48UZKHwZpJvTxA++eCS6VRqogKKN/RwfZXSKSSuGxwXbUkt2+wQgZpsYSdgIQdMCrI
+IIG1pBQMYHEqg4ntmlRtnqNBwwuQXZdXdaWlwvD/vGhoS9gIBX7wGnZEgE4cRA6BJwkVolWRJXqfaKSH
+WB9Wbdm8LhY3ZH+DI9gtbvHACOU3IDzqjP44GmCVRlh3wCeJCysshMKmDNWpahuiAdXULtKkMfIgt+uY
+fhAwbVvJnQZHYRzRpafP2yrFixsgDMx50cQmjkZRbKxU0IAlyTYV6gZHmZZYcmFNhuRxSWjYylrIuAER
+14KISywT5RAMypqZg/QOplQuj0GRS6fTEWdIYoMEQGsT5Td1vYephdJkXghH0o7JTMgSuKvSSx1I+Iar
+f8YN2EcVLMnjp/r1m5TOBg5PInDkl8sExgFp2xSLrMAWhnEsb0kdaBDgFWmjMm/KPXgII3QWGky4Epix
+O4CAmKrBYcusN/liBW4YAq/FQ03BAZt7+NAjMRvY7iPbsFYLQRuLyAYDQzo0YLpbN9y3sy7W5vULcOgd
+A6UnTBf7vsBxBCLQURUTVdzUmGGl1ppQxdxgYG+0S73hoBFYOQSWFVzsvYU/4ANmzrJ2SAavjs1TbjrD
+rlbWDTkvioAFAPasUEkd9vWMBegKIz71R1Sq8PbkzeuDdwevL58fHr88PP6V9hLKDC9bYkMtg2qpSvF2
+X43ni+ALKkmlP6IJHBGe8utQtyeZmimYc+l5yOeKp1mPgwjZD8aKaajwbcB4UazX2ntQ1qTCJPjERVs3
+sCTd3yBfjn0cUwQCll5a5Cdc4ALTcnObJrTh+F9ddCBlNh6ZkiMqSnCjVFA66ZsjjRMYx0khOiLlN1nu
+THqi8q5YgNCAEEj1OoV8rNKFq5Eqv0Zcbwmyn+jgWC/9XsBLqiSmTOASe0YDmbiBc8BUAq6G1NmdeuIh
+BxjPAB0KNLyury+5qKhoLpowo0RHaIb0gDnaeoG2jKKag+ndTLT20pqocwmlVLzPZ6NUcFEVBQYTVxs+
+1FIHmGLeLpeyqlU5hXtBdwv91LSGv2qOWopWJPThF4iryC9hRcFD4irsL3HG/H7rSgz/GN8Mq3RzjMwF
+8VgeSXT1dg5p3OexK+0el2eSy1V10BELPhjqnk94pFBWJI6ED5g/fx2qlC7RKBF98LOxMP+6Eh3Jh57M
+kF27QNxPuUm6iFqXsdDjjCXQYeuBs0nqKJGZBNqap62cEyv3Zh2Js5SNYCZaoXhj9qJvMxGe1AbYY9Z3
+GRQ8FU4u5CAZu9RBQp7i7yDlwbqXCzncoKMjdeAW22gvEOpGh+u3SGnsCxiYfInn9tmP6psXn796A2/V
+VBqgPIBR232EBoDtHVgQBnDaVLStMcS4QUJzR0dIwDGuwvUY3m4z6bDdbAjP58GAjMio5i0vD+9Xd5OD
+vqcOLPBE2I05sORzC3nh7NMBw9RUehRMgwojj8e5tzIQgp1jd1jamE3Wy3p0KPOW4+0ypEWtHJdTnOZN
+2bKHuPY9PNGlLx/5YZ+g6K5k77PF95XqYaasK2IlL8/YzhnkwnKHrzH1DnhkIgaEiC0eTAI1eTCRvBHB
+GFnC0wfYn2OM7GPaPeNw4rTz6KuXEd4ywawAtspgnOqHKJ2SqRMo5euUBeN3FkCPWd2Hras3d7fFCbCa
++YC4noj+Oll/fIm06z6UZklbZVwpN4OP5i414dWXnFkm5HdIVLM/JH+6C8YZ0eyuneIAs4/j0Nr3cQxj
+GEbl3cmvAYi2efZQG6BBzMawFbWOPkwh7FEYcOouje3lEufjA6eY8AsyH1g7lXaYkvglU9AUpchAnRmd
+tvfyffb71A5xfHvf8xEDjmvT2T27+Tsz/E6UduPDoLvUQ31ZqJJKDtSFNjdHoBfwUvd24gUdhAK/oz2n
+Vuy3IjD/h3XDo0x2Npjmkuot7idR2YQNk9wi14fcH1psYnJFQ3wuITWvV5JOJusGjzMUJDWl8HRc1ovW
+Ho9zy5bmgi+RS7wR4rY3VH92AmioboHaOOdlwgJAUBj6bAVTtdjKxQukHRUjriC29wJV3a8M1yzLVv9i
+9t9iSpE17Ja92+vjz0PinPFJz1fCMYfwp/sP9+MhXa9lksJKQ7f30bEEvTTw7AEeg4Xj/k0jvDJ47zbL
+lpXwNzFfOz2Qru1CJo6meyk/laDtOi1y8AP2QTf2dFnvb2mKF9sMXrfb8yy98UYn6hqGpcGtetNK4KNv
+PiIEc06p/McjCISClMyIv986OzBYu1bk5zdDyrAcoUru2uRKMF0vxP6L70JiCAaMbcb9i6Lq3D9J9dYM
+d96MHNofPu/Y3dQhbc/Ise+XYtsM5cjstiS0NbILQUTU2/LvtKiTWVQGKKIpIHuK6WaLyiiwqCIbAZ0S
+Dg4OeIJUgvxZQhtHBgFfAeT2xNkmb+JPB3yFJ1WXRrndHEWm/LL7Eld6ygKedTXBjr8noon4ia0BPZsw
+12xw+xqm3ELtjss4pp5E/vD7SXag7K+VHXDOq02htwhzScSsxDvCt2v8N/CtgDss9Gw/0BeVcOPlMahz
+JbNSdQHBXlpMXk2L6JBvffP5NLmI3na409yH/YZnOhwij0/eKtsCsa/wDkUAJr6hMYpg+D3rcAgjQ+eF
+1GIdQ/pSSZltVJGAzCtpczmWVtSntPS7+/IVodridzydDicpepJz54Q/U8RuNFVQ4KzLAjzUPNuE4iqi
+A9ur6fRK1BI2IbnOGg8r8dYD7EKSLMbfBFLodJlSjrcsWuwCNfr0qy6qN0Wt3RkKprsu3DbPmoB1bWbg
+2lZ4zjOjSec/OmMXdHB+/uT8wc8XP5y/v51G8M/T7y+evv/tyYU+VG/iNNOTzcwoevyTnv3+2ftbnmYn
+KWkGRPkR4Qijup0HAH0eTS8ePZmI/X31il4ASXplqi2+DWg+DDrio8lA9aFlhUczabMhA6iLtlrIS33Z
+03wcpM/6FS7GEfA9wZifoDaQyN5ryniBdXNjdKZBog56HwhPYNWTc+1/DFQaPGBSx1/jkXepf/iuv8Hz
+oUjzYH+6H3ZmUXqgz8aY/Dl/pbb3OacCWH20JlhGU2fy3me9HBpyQEmOw6A05IB2JDEFUP2bobBGAon8
+KP7qytXeCnOYj8q2XiFS3BNrvFFbT7v07VCkPlL5Ou6WPS5KEhweuw/YkTkwJHtSl04De32UqnEmAhuU
+73tsMaQTmqwNKefHniXdT1FEHAWp8fB9TgeCWPEgjCx+r8jZKn2yZTvP0oW6A1AHfDAJtl81v1f2z8mH
+YQjJlbwn4u8UovbUTYOsKG5q0ZZ4FqSTVobcrzWTlJRhK6WgG5JZxoE6nFDPwVz30rNYmQV48Qocai0U
+KfKWSo3Mg/IoM8USLrKJwSUrCQRTtXU7Lt61HlQlxaxY4MlNZgOAXzaDkagIiyQw5OrrMxAZqAZk0maC
+loWpxfV1U7pmgx978O0TyLRXJt+PmSEIfWltStVjSgTNfQYq7lDqeMunFAE3Ok1Kz8FWqZvqVUxInbQe
+qkHeSXiLeqDoL7KPMtDnRdQYUidA1A1B7x8//u3yAiIGOPzudRp1o7SbIZIsL7VoLoFgmsvkKZGhgLNZ
+e037O+Zjj7QzVTMXTKfh0/Nnj/8HGYQH/TPscqt3KirUIlQBbjrlUBbqxIRPtDSR9w/e3z7S+NSJNF21
+AiWkdK80ZD2qKxioTVSFshj3MFffcVGo1CkrlsB8YOt+drJF2IzVSMf51mebeIezIq1zvcqfnUXGp3Ih
+04903tRVTqWGtrBnptrtOWwIHou7ZmlthTvbZ2a7B23ORwuhTkF407NzOFZ6sh5o4uxOfbmbnYX69oay
+cMcnOfuHCZwsAxtSLJlwNCQfh43Z/5J13T7SKVyvmnb9XoStAkMo5GapQ821XV8qna8i8WNPL9Qpcbh+
+kj7LIeHhBV+S367MdjLg4VQpS2mxcm2B87+M4GN8dP8G4QJAYQ+0eUpObLGCXGLRUF8dL8YXfHMZ2YRp
+nNPv4YcOvk5UldRNq3vBXh1735mL+7cHaY/JdUnX7HCQzVb14wvPAPBoHMErLORTumyyH1GKCIgAVuGx
+gX/PD0tTMRSWhmOSCUgKkY1LEK+0LrfEKMfdaPOygumGCGL+e5ODdL88c6pk8+n7t+di7udnQmf1liez
+7bb5ponoeRilP52ndq9f7mL47irE61i44obC0lFoEgrHe+71jMu1Jm0cX744FqUtz/fQFLXduX4Rwh5+
+H2js41ELPuirHF0YbNLuKxj6n/gMAVGeuc9A+k5qHwqPqjWqnSFkV43VsUF3jWF3cicykLJNVCEzffjQ
+k5L4fsZSGe0yMYNjh2vv29WfZFV3+3Hlr8EpwDy6M6sS8/GiwO5jg5+OAYoxZAn6BZ15pjllL2OgWKrz
+47Ys8EBB8t0o+j7ZnmnwEWaZYsYhsEuqMhnKhpbAs/panUDA8S/iFrSZNkLdyoE4WvCngRDDwMfTOVmi
+b9aorzvxmhX4/BvZ0Ce5LtPd0HtYv1BD5LRRI0WWvJPVHFulkLa8Ozh9fnJ2gIdP6qdziwkxvDuaTiFY
+gJ3Tffgz1eCJ1BoM2iFdWumGIxV3nR4rNQ+rih09ftqpLyxhxG+x17dOc0wHv4BwHhfLx3T1/8v7B/D4
+RX5SBS2I6QpeXD2x/nWQFUdIvRSpC8k6R8NSJyFGNjNHfGR3/wdegzH9K1MAAA==
 `,
 	},
 }
@@ -102,13 +102,14 @@ void startRuby() {
 /// \brief Stops running the (single) Ruby instance.
 ///
 int stopRuby() {
+  int result = 0;
   pthread_mutex_lock(&rubyMutex);
   if (rubyRunning) {
     rubyRunning = 0;
-    return ruby_cleanup(0);
+    result = ruby_cleanup(0);
   }
   pthread_mutex_unlock(&rubyMutex);
-  return 0;
+  return result;
 }
 
 /// \brief Is ruby already running?
@@ -465,6 +466,165 @@ VALUE IPyRubyData_AddMetadata(
   return Qnil;
 }
 
+/// \brief Adds a key/value pair to the Transient map of a Data object.
+///
+/// Takes the Data object at `objId` from the IPyRubyStore and adds the 
+/// key/value (for example a `display_id`) to the Data's Transient map. 
+///
+/// The Object ID is a FIXNUM, the key and value are both Ruby Strings. 
+///
+VALUE IPyRubyData_AddTransient(
+  VALUE recv,
+  VALUE objIdObj,
+  VALUE keyObj,
+  VALUE valueObj
+) {
+  uint64_t objId = 0;
+
+  DEBUG_Log("IPyRubyData_AddTransient\n");
+  // check each argument.... do nothing if they are not valid
+  //
+  if (FIXNUM_P(objIdObj)) {
+    objId = NUM2LONG(objIdObj);
+  } else return Qnil;
+  DEBUG_Log2("  objId: %ld\n", objId);
+  if (!RSTRING_P(keyObj) || !RSTRING_P(valueObj)) return Qnil;
+
+  assert(objId);
+  GoIPyRubyData_AddTransient(
+    objId,
+    StringValuePtr(keyObj),   RSTRING_LEN(keyObj),
+    StringValuePtr(valueObj), RSTRING_LEN(valueObj)
+  );
+  return Qnil;
+}
+
+/// \brief Publishes a Data object to the Jupyter front-end. 
+///
+/// Takes (and removes) the Data object at `objId` from the IPyRubyStore 
+/// and publishes it as a display_data or, if update is truthy, as an 
+/// update_display_data (in which case the Data's Transient map must 
+/// contain a `display_id`). 
+///
+/// Returns true if the data was published. 
+///
+VALUE IPyRuby_Display(VALUE recv, VALUE objIdObj, VALUE updateObj) {
+  DEBUG_Log("IPyRuby_Display\n");
+  if (!FIXNUM_P(objIdObj)) return Qfalse;
+
+  int published = GoIPyRuby_Display(NUM2LONG(objIdObj), RTEST(updateObj));
+  return published ? Qtrue : Qfalse;
+}
+
+/// \brief Clears the outputs of the current cell. 
+///
+/// If wait is truthy, the outputs are only cleared once the next output 
+/// arrives. Returns true if the clear_output was published. 
+///
+VALUE IPyRuby_ClearOutput(VALUE recv, VALUE waitObj) {
+  DEBUG_Log("IPyRuby_ClearOutput\n");
+  int published = GoIPyRuby_ClearOutput(RTEST(waitObj));
+  return published ? Qtrue : Qfalse;
+}
+
+/// \brief Requests a line of input from the Jupyter front-end.
+///
+/// The prompt is a Ruby String and password is truthy if the front-end 
+/// should not echo the user's input. Returns the line (without its 
+/// newline) as a Ruby String, or nil if the front-end does not allow 
+/// stdin. 
+///
+VALUE IPyRuby_ReadLine(
+  VALUE recv,
+  VALUE promptObj,
+  VALUE passwordObj
+) {
+  char    *prompt    = "";
+  uint64_t promptLen = 0;
+
+  DEBUG_Log("IPyRuby_ReadLine\n");
+  if (RSTRING_P(promptObj)) {
+    prompt    = StringValuePtr(promptObj);
+    promptLen = RSTRING_LEN(promptObj);
+  }
+  DEBUG_Log2("  prompt: %s\n", prompt);
+
+  char *line = GoIPyRuby_ReadLine(prompt, promptLen, RTEST(passwordObj));
+  if (!line) return Qnil;
+
+  VALUE result = rb_utf8_str_new_cstr(line);
+  free(line);
+  return result;
+}
+
+/// \brief Registers a comm target (whose comm events are dispatched to 
+/// IPyRubyCommDispatch) with the kernel's CommManager. 
+///
+/// Returns true if the target was registered. 
+///
+VALUE IPyRuby_CommRegisterTarget(VALUE recv, VALUE targetObj) {
+  DEBUG_Log("IPyRuby_CommRegisterTarget\n");
+  if (!RSTRING_P(targetObj)) return Qfalse;
+
+  int registered = GoIPyRuby_CommRegisterTarget(
+    StringValuePtr(targetObj), RSTRING_LEN(targetObj)
+  );
+  return registered ? Qtrue : Qfalse;
+}
+
+/// \brief Opens a comm with the front-end's target, sending it the 
+/// (JSON encoded) data. 
+///
+/// Returns the new comm's id as a Ruby String, or nil if the comm could 
+/// not be opened. 
+///
+VALUE IPyRuby_CommOpen(VALUE recv, VALUE targetObj, VALUE dataObj) {
+  DEBUG_Log("IPyRuby_CommOpen\n");
+  if (!RSTRING_P(targetObj) || !RSTRING_P(dataObj)) return Qnil;
+
+  char *commId = GoIPyRuby_CommOpen(
+    StringValuePtr(targetObj), RSTRING_LEN(targetObj),
+    StringValuePtr(dataObj),   RSTRING_LEN(dataObj)
+  );
+  if (!commId) return Qnil;
+
+  VALUE result = rb_utf8_str_new_cstr(commId);
+  free(commId);
+  return result;
+}
+
+/// \brief Sends the (JSON encoded) data to an open comm. 
+///
+/// Returns true if the data was sent. 
+///
+VALUE IPyRuby_CommSend(VALUE recv, VALUE commIdObj, VALUE dataObj) {
+  DEBUG_Log("IPyRuby_CommSend\n");
+  if (!RSTRING_P(commIdObj) || !RSTRING_P(dataObj)) return Qfalse;
+
+  int sent = GoIPyRuby_CommSend(
+    StringValuePtr(commIdObj), RSTRING_LEN(commIdObj),
+    StringValuePtr(dataObj),   RSTRING_LEN(dataObj),
+    0
+  );
+  return sent ? Qtrue : Qfalse;
+}
+
+/// \brief Closes an open comm, sending it the (JSON encoded) data. 
+///
+/// Returns true if the comm was closed. 
+///
+VALUE IPyRuby_CommClose(VALUE recv, VALUE commIdObj, VALUE dataObj) {
+  DEBUG_Log("IPyRuby_CommClose\n");
+  if (!RSTRING_P(commIdObj) || !RSTRING_P(dataObj)) return Qfalse;
+
+  int closed = GoIPyRuby_CommSend(
+    StringValuePtr(commIdObj), RSTRING_LEN(commIdObj),
+    StringValuePtr(dataObj),   RSTRING_LEN(dataObj),
+    1
+  );
+  return closed ? Qtrue : Qfalse;
+}
+
 /// \brief Initialize the IPyRubyData class inside ruby
 ///
 void Init_IPyRubyData(void) {
@@ -474,39 +634,56 @@ void Init_IPyRubyData(void) {
   rb_define_global_function("IPyRubyData_AddData",         IPyRubyData_AddData,         3);
   rb_define_global_function("IPyRubyData_AppendTraceback", IPyRubyData_AppendTraceback, 2);
   rb_define_global_function("IPyRubyData_AddMetadata",     IPyRubyData_AddMetadata,     4);
+  rb_define_global_function("IPyRubyData_AddTransient",    IPyRubyData_AddTransient,    3);
+  rb_define_global_function("IPyRuby_Display",             IPyRuby_Display,             2);
+  rb_define_global_function("IPyRuby_ClearOutput",         IPyRuby_ClearOutput,         1);
+  rb_define_global_function("IPyRuby_ReadLine",            IPyRuby_ReadLine,            2);
+  rb_define_global_function("IPyRuby_CommRegisterTarget",  IPyRuby_CommRegisterTarget,  1);
+  rb_define_global_function("IPyRuby_CommOpen",            IPyRuby_CommOpen,            2);
+  rb_define_global_function("IPyRuby_CommSend",            IPyRuby_CommSend,            2);
+  rb_define_global_function("IPyRuby_CommClose",           IPyRuby_CommClose,           2);
 }
 
 /// \brief protectedEvalString actually makes the rb_funcall required to 
-/// IPyRubyEval the Ruby code in the string provided. 
+/// evaluate the Ruby code in the string provided using the Ruby evaluation 
+/// method (`IPyRubyEval`, `IPyRubyHelperEval` or `IPyRubyExpressionEval`) 
+/// provided. 
 ///
 /// This indirection allows exceptions in the Ruby code in the string to 
 /// be cleanly rescued by the ANSI-C code. 
 ///
-static VALUE protectedEvalString(VALUE evalStr) {
-  assert(evalStr)
+static VALUE protectedEvalString(VALUE args) {
+  assert(args);
+  VALUE evalStr    = rb_ary_pop(args);
+  VALUE evalMethod = rb_ary_pop(args);
+
+  assert(evalStr);
+  assert(evalMethod);
   DEBUG_Log("before protectedEvalString::rb_funcall\n");
   VALUE result = rb_funcall(
     Qnil,
-    rb_intern("IPyRubyEval"),
+    SYM2ID(evalMethod),
     1,
     evalStr
   );
-  // This will NOT be called IF the IPyRubyEval raises an exception...
+  // This will NOT be called IF the evalMethod raises an exception...
   DEBUG_Log("after protectedEvalString::rb_funcall\n");
   return result;
 }
 
-/// \brief Evaluate the string aStr in the TOPLEVEL_BINDING and returns 
-/// any result as a Go Data object located in the IPyRubyStore at the 
-/// returned objId. 
+/// \brief Evaluate the string evalCodeCStr using the Ruby method named 
+/// evalMethodCStr and returns any result as a Go Data object located in 
+/// the IPyRubyStore at the returned objId. 
 ///
-uint64_t evalRubyString(
+static uint64_t evalRubyStringWith(
+  const char* evalMethodCStr,
   const char* evalNameCStr,
   const char* evalCodeCStr
 ) {
   assert(rubyRunning);
   //int codeLoaded = isRubyCodeLoaded("IPyRubyData.rb");
   assert(isRubyCodeLoaded("IPyRubyData.rb"));
+  assert(evalMethodCStr); assert(strlen(evalMethodCStr));
   assert(evalNameCStr); assert(strlen(evalNameCStr));
   assert(evalCodeCStr); assert(strlen(evalCodeCStr));
   
@@ -514,13 +691,18 @@ uint64_t evalRubyString(
   pthread_mutex_lock(&rubyMutex);
 
   //VALUE evalName = rb_str_new_cstr(evalNameCStr);
-  VALUE evalCode = rb_str_new_cstr(evalCodeCStr);
+  VALUE evalCode   = rb_str_new_cstr(evalCodeCStr);
+  VALUE evalMethod = ID2SYM(rb_intern(evalMethodCStr));
+  VALUE evalArgs   = rb_ary_new();
   assert(evalCode);
+  assert(evalArgs);
+  rb_ary_push(evalArgs, evalMethod);
+  rb_ary_push(evalArgs, evalCode);
   
   DEBUG_Log("Before rb_protect\n");
   int loadFailed = 0;
   uint64_t result = 0;
-  VALUE rbResult = rb_protect(protectedEvalString, evalCode, &loadFailed);
+  VALUE rbResult = rb_protect(protectedEvalString, evalArgs, &loadFailed);
   assert(rbResult);
   if (RB_FIXNUM_P(rbResult)) { result = FIX2LONG(rbResult); }
   DEBUG_Log2("After rb_protect   rbResult: %ld\n", rbResult);
@@ -533,16 +715,27 @@ uint64_t evalRubyString(
     assert(errMesg);
     VALUE errStr  = rb_sprintf("%"PRIsVALUE, errMesg);
     assert(errStr);
+    const char *errClass  = rb_obj_classname(errMesg);
+    VALUE       backtrace = rb_funcall(errMesg, rb_intern("backtrace"), 0);
+    rb_set_errinfo(Qnil);
     
     result = GoIPyRubyData_New();
     assert(result);
     GoIPyRubyData_AddData(result,
-      "ename", strlen("ename"), "ERROR", strlen("ERROR"));
+      "ename", strlen("ename"), (char*)errClass, strlen(errClass));
     GoIPyRubyData_AddData(result,
       "evalue", strlen("evalue"), StringValuePtr(errStr), RSTRING_LEN(errStr));
     char* tracebackMsg = "protectedEvalString FAILED";
     GoIPyRubyData_AppendTraceback(result,
       tracebackMsg, strlen(tracebackMsg));
+    if (RB_TYPE_P(backtrace, T_ARRAY)) {
+      for (long i = 0; i < RARRAY_LEN(backtrace); i++) {
+        VALUE frame = rb_ary_entry(backtrace, i);
+        if (!RB_TYPE_P(frame, T_STRING)) continue;
+        GoIPyRubyData_AppendTraceback(result,
+          StringValuePtr(frame), RSTRING_LEN(frame));
+      }
+    }
     GoIPyRubyData_AddData(result,
       "status", strlen("status"), "error", strlen("error"));
   }
@@ -551,3 +744,43 @@ uint64_t evalRubyString(
   DEBUG_Log2("Finished evalRubyString on [%s]\n", evalNameCStr);
   return result;
 }
+
+/// \brief Evaluate the string aStr in the $IPyRubyBinding and returns 
+/// any result as a Go Data object located in the IPyRubyStore at the 
+/// returned objId. 
+///
+uint64_t evalRubyString(
+  const char* evalNameCStr,
+  const char* evalCodeCStr
+) {
+  return evalRubyStringWith("IPyRubyEval", evalNameCStr, evalCodeCStr);
+}
+
+/// \brief Evaluate the (kernel internal) helper code in the string aStr 
+/// and returns any result as a Go Data object located in the 
+/// IPyRubyStore at the returned objId. 
+///
+/// Unlike evalRubyString, the helper code is evaluated without 
+/// IPyRubyEval's error reporting, so helpers may freely raise and rescue 
+/// their own exceptions. 
+///
+uint64_t evalRubyHelperString(
+  const char* evalNameCStr,
+  const char* evalCodeCStr
+) {
+  return evalRubyStringWith("IPyRubyHelperEval", evalNameCStr, evalCodeCStr);
+}
+
+/// \brief Evaluate the (user_expressions) expression in the string aStr 
+/// in the $IPyRubyBinding and returns any result as a Go Data object 
+/// located in the IPyRubyStore at the returned objId. 
+///
+/// Unlike evalRubyString, the expression is not evaluated as a cell (so 
+/// it can not be interrupted). 
+///
+uint64_t evalRubyExpressionString(
+  const char* evalNameCStr,
+  const char* evalCodeCStr
+) {
+  return evalRubyStringWith("IPyRubyExpressionEval", evalNameCStr, evalCodeCStr);
+}
@@ -8,8 +8,11 @@ package goIPyRubyAdaptor
 import "C"
 
 import (
+  "encoding/base64"
+  "encoding/json"
   "errors"
   "fmt"
+  "strings"
   "unsafe"
   
   "github.com/davecgh/go-spew/spew"
@@ -36,6 +39,184 @@ func GoToggleIPyRubyDebugging() {
   IPyRubyDebugging = !IPyRubyDebugging
 }
 
+// The receipt of the execute_request currently being evaluated, used to 
+// request input from the front-end (or nil between evaluations). 
+//
+var stdinReceipt *tk.MsgReceipt
+
+// Request a line of input from the Jupyter front-end (on behalf of the 
+// Ruby IPyRuby_ReadLine function). 
+//
+// Returns a (C.malloc'ed) copy of the line which the caller must free, or 
+// nil if no front-end is able to answer the request. 
+//
+//export GoIPyRuby_ReadLine
+func GoIPyRuby_ReadLine(
+  promptPtr *C.char,
+  promptLen  C.int,
+  password   C.int,
+) *C.char {
+  if stdinReceipt == nil {
+    return nil
+  }
+  prompt := C.GoStringN(promptPtr, promptLen)
+  line, err := stdinReceipt.ReadLine(prompt, password != 0)
+  if err != nil {
+    if IPyRubyDebugging { fmt.Printf("GoIPyRuby_ReadLine: %s\n", err) }
+    return nil
+  }
+  return C.CString(line)
+}
+
+// The kernel's CommManager (see GoAdaptor.RegisterComms), used by the 
+// Ruby IPyRuby_Comm* functions, together with the Ruby state in which 
+// comm events are dispatched (to IPyRubyCommDispatch). 
+//
+var rubyComms      *tk.CommManager
+var rubyCommsState *RubyState
+
+// Decode the (JSON object) comm data passed from Ruby. 
+//
+func decodeCommData(dataPtr *C.char, dataLen C.int) map[string]interface{} {
+  data := make(map[string]interface{})
+  if err := json.Unmarshal([]byte(C.GoStringN(dataPtr, dataLen)), &data); err != nil {
+    if IPyRubyDebugging { fmt.Printf("decodeCommData: %s\n", err) }
+  }
+  return data
+}
+
+// Return a Ruby Array literal of the (binary) buffers, each of which is 
+// passed base64 encoded (the helper code is passed to Ruby as a C 
+// string, so can not contain NUL bytes). 
+//
+func rubyBuffersLiteral(buffers [][]byte) string {
+  literals := make([]string, len(buffers))
+  for i, buffer := range buffers {
+    literals[i] = rubyStringLiteral(base64.StdEncoding.EncodeToString(buffer)) +
+      ".unpack1('m0')"
+  }
+  return "[" + strings.Join(literals, ", ") + "]"
+}
+
+// Dispatch a comm event ("open", "msg" or "close"), with its data and 
+// buffers, to the Ruby IPyRubyCommDispatch function. 
+//
+func dispatchRubyCommEvent(
+  event   string,
+  comm    *tk.Comm,
+  data    map[string]interface{},
+  buffers [][]byte,
+) {
+  dataJSON, err := json.Marshal(data)
+  if err != nil {
+    dataJSON = []byte("{}")
+  }
+  helperCode := fmt.Sprintf(
+    "IPyRubyCommDispatch(%s, %s, %s, %s, %s)",
+    rubyStringLiteral(event),
+    rubyStringLiteral(comm.ID),
+    rubyStringLiteral(comm.TargetName),
+    rubyStringLiteral(string(dataJSON)),
+    rubyBuffersLiteral(buffers),
+  )
+  result := rubyCommsState.GoEvalRubyHelperString("IPyRubyCommDispatch", helperCode)
+  if execErr := rubyExecutionError(result); execErr != nil {
+    fmt.Printf("IPyRuby comm %s handler FAILED: %s\n", event, execErr)
+  }
+}
+
+// Dispatch the comm's msg and close events to Ruby. 
+//
+func onRubyCommEvents(comm *tk.Comm) {
+  comm.OnMsg(func(comm *tk.Comm, data map[string]interface{}, buffers [][]byte) {
+    dispatchRubyCommEvent("msg", comm, data, buffers)
+  })
+  comm.OnClose(func(comm *tk.Comm, data map[string]interface{}, buffers [][]byte) {
+    dispatchRubyCommEvent("close", comm, data, buffers)
+  })
+}
+
+// Register the comm target (on behalf of the Ruby 
+// IPyRuby_CommRegisterTarget function) so that comms opened by the 
+// front-end are dispatched to Ruby. 
+//
+//export GoIPyRuby_CommRegisterTarget
+func GoIPyRuby_CommRegisterTarget(targetPtr *C.char, targetLen C.int) C.int {
+  if rubyComms == nil {
+    return 0
+  }
+  rubyComms.RegisterTarget(
+    C.GoStringN(targetPtr, targetLen),
+    func(comm *tk.Comm, data map[string]interface{}, buffers [][]byte) {
+      onRubyCommEvents(comm)
+      dispatchRubyCommEvent("open", comm, data, buffers)
+    },
+  )
+  return 1
+}
+
+// Open a comm with the front-end's target (on behalf of the Ruby 
+// IPyRuby_CommOpen function). 
+//
+// Returns a (C.malloc'ed) copy of the new comm's id which the caller must 
+// free, or nil if the comm could not be opened. 
+//
+//export GoIPyRuby_CommOpen
+func GoIPyRuby_CommOpen(
+  targetPtr *C.char,
+  targetLen  C.int,
+  dataPtr   *C.char,
+  dataLen    C.int,
+) *C.char {
+  if rubyComms == nil {
+    return nil
+  }
+  comm, err := rubyComms.Open(
+    C.GoStringN(targetPtr, targetLen),
+    decodeCommData(dataPtr, dataLen),
+  )
+  if err != nil {
+    if IPyRubyDebugging { fmt.Printf("GoIPyRuby_CommOpen: %s\n", err) }
+    return nil
+  }
+  onRubyCommEvents(comm)
+  return C.CString(comm.ID)
+}
+
+// Send data to (or, if `closeComm` is non-zero, close) an open comm (on 
+// behalf of the Ruby IPyRuby_CommSend and IPyRuby_CommClose functions). 
+//
+// Returns non-zero if the data was sent. 
+//
+//export GoIPyRuby_CommSend
+func GoIPyRuby_CommSend(
+  commIdPtr *C.char,
+  commIdLen  C.int,
+  dataPtr   *C.char,
+  dataLen    C.int,
+  closeComm  C.int,
+) C.int {
+  if rubyComms == nil {
+    return 0
+  }
+  comm := rubyComms.Comm(C.GoStringN(commIdPtr, commIdLen))
+  if comm == nil {
+    return 0
+  }
+  data := decodeCommData(dataPtr, dataLen)
+  var err error
+  if closeComm != 0 {
+    err = comm.Close(data)
+  } else {
+    err = comm.Send(data)
+  }
+  if err != nil {
+    if IPyRubyDebugging { fmt.Printf("GoIPyRuby_CommSend: %s\n", err) }
+    return 0
+  }
+  return 1
+}
+
 // Create a new Data object and store it in the IPyRubyStore.
 //
 // Return the GoUInt64 key to the new object in the IPyRubyStore.
@@ -131,6 +312,70 @@ func GoIPyRubyData_AddMetadata(
   tk.StoreData_AddMetadata(objId, mimeType, metaKey, dataValue)
 }
 
+// Add the key/value pair to the Transient map of the Data object.
+//
+// Takes the Data object at `objId` from the IPyRubyStore and adds the 
+// key/value (for example a display_id) to the Data's Transient map. 
+//
+//export GoIPyRubyData_AddTransient
+func GoIPyRubyData_AddTransient(
+  objId     uint64,
+  keyPtr   *C.char,
+  keyLen    C.int,
+  valuePtr *C.char,
+  valueLen  C.int,
+) {
+  key   := C.GoStringN(keyPtr, keyLen)
+  value := C.GoStringN(valuePtr, valueLen)
+  tk.StoreData_AddTransient(objId, key, value)
+}
+
+// Publish the Data object, at `objId` in the IPyRubyStore, to the Jupyter 
+// front-end (on behalf of the Ruby IPyRuby_Display function) as either a 
+// display_data or (if update is non-zero) an update_display_data. The 
+// Data object is removed from the IPyRubyStore. 
+//
+// Returns 1 if the data was published. 
+//
+//export GoIPyRuby_Display
+func GoIPyRuby_Display(objId uint64, update C.int) C.int {
+  data := getEvalData(objId, "GoIPyRuby_Display")
+  tk.StoreData_Delete(objId)
+  if stdinReceipt == nil {
+    return 0
+  }
+
+  var err error
+  if update != 0 {
+    err = stdinReceipt.PublishUpdateDisplayData(data)
+  } else {
+    err = stdinReceipt.PublishDisplayData(data)
+  }
+  if err != nil {
+    if IPyRubyDebugging { fmt.Printf("GoIPyRuby_Display: %s\n", err) }
+    return 0
+  }
+  return 1
+}
+
+// Clear the outputs of the current cell (on behalf of the Ruby 
+// IPyRuby_ClearOutput function). If wait is non-zero, the outputs are 
+// only cleared once the next output arrives. 
+//
+// Returns 1 if the clear_output was published. 
+//
+//export GoIPyRuby_ClearOutput
+func GoIPyRuby_ClearOutput(wait C.int) C.int {
+  if stdinReceipt == nil {
+    return 0
+  }
+  if err := stdinReceipt.PublishClearOutput(wait != 0); err != nil {
+    if IPyRubyDebugging { fmt.Printf("GoIPyRuby_ClearOutput: %s\n", err) }
+    return 0
+  }
+  return 1
+}
+
 // A representation of the Ruby state.
 //
 // NOTE: Since Ruby is not reentrant, there can only be one Ruby instance.
@@ -226,13 +471,72 @@ func (rs *RubyState) GoEvalRubyString(
   defer C.free(unsafe.Pointer(rubyCodeCStr))
 
   objId := uint64(C.evalRubyString(rubyCodeNameCStr, rubyCodeCStr))
+  return getEvalData(objId, "GoEvalRubyString")
+}
+
+// Evaluate the (kernel internal) helper code in the String aGoStr in the 
+// (single) Ruby instance. 
+//
+// Unlike GoEvalRubyString, the helper code is evaluated (by 
+// IPyRubyHelperEval) without IPyRubyEval's error reporting. 
+//
+func (rs *RubyState) GoEvalRubyHelperString(
+  rubyCodeName, rubyCodeStr string,
+) tk.Data {
+  if IPyRubyDebugging {
+    fmt.Printf("GoEvalRubyHelperString\n")
+    fmt.Printf("  rubyCodeName: [%s]\n", rubyCodeName)
+    fmt.Printf("   rubyCodeStr: [%s]\n", rubyCodeStr)
+  }
+  
+  rubyCodeNameCStr := C.CString(rubyCodeName)
+  defer C.free(unsafe.Pointer(rubyCodeNameCStr))
+  
+  rubyCodeCStr := C.CString(rubyCodeStr)
+  defer C.free(unsafe.Pointer(rubyCodeCStr))
+
+  objId := uint64(C.evalRubyHelperString(rubyCodeNameCStr, rubyCodeCStr))
+  return getEvalData(objId, "GoEvalRubyHelperString")
+}
+
+// Evaluate the (user_expressions) expression in the String aGoStr in the 
+// (single) Ruby instance. 
+//
+// Unlike GoEvalRubyString, the expression is evaluated (by 
+// IPyRubyExpressionEval) without marking the user's code as being 
+// evaluated, so it can not be interrupted. The expression must not be 
+// empty. 
+//
+func (rs *RubyState) GoEvalRubyExpressionString(
+  rubyCodeName, rubyCodeStr string,
+) tk.Data {
+  if IPyRubyDebugging {
+    fmt.Printf("GoEvalRubyExpressionString\n")
+    fmt.Printf("  rubyCodeName: [%s]\n", rubyCodeName)
+    fmt.Printf("   rubyCodeStr: [%s]\n", rubyCodeStr)
+  }
+  
+  rubyCodeNameCStr := C.CString(rubyCodeName)
+  defer C.free(unsafe.Pointer(rubyCodeNameCStr))
+  
+  rubyCodeCStr := C.CString(rubyCodeStr)
+  defer C.free(unsafe.Pointer(rubyCodeCStr))
+
+  objId := uint64(C.evalRubyExpressionString(rubyCodeNameCStr, rubyCodeCStr))
+  return getEvalData(objId, "GoEvalRubyExpressionString")
+}
+
+// Get (a deep copy of) the Data object, at `objId` in the IPyRubyStore, 
+// returned by one of the evalRubyString functions. 
+//
+func getEvalData(objId uint64, evalFuncName string) tk.Data {
   if objId == 0 {
     return tk.Data{
       Data: tk.MIMEMap{
         "ename":     "ERROR",
         "evalue":    "no return value from evalRubyString",
-        "traceback": []string{ "GoEvalRubyString" },
-        "state":     "error",
+        "traceback": []string{ evalFuncName },
+        "status":    "error",
       },
       Metadata: tk.MIMEMap{},
       Transient: tk.MIMEMap{},
@@ -245,8 +549,8 @@ func (rs *RubyState) GoEvalRubyString(
       Data: tk.MIMEMap{
         "ename":     "ERROR",
         "evalue":    "no data object in the object store",
-        "traceback": []string { "GoEvalRubyString" },
-        "state":     "error",
+        "traceback": []string { evalFuncName },
+        "status":    "error",
       },
       Metadata: tk.MIMEMap{},
       Transient: tk.MIMEMap{},
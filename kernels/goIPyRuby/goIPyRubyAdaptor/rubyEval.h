@@ -38,4 +38,14 @@ extern uint64_t evalRubyString(
   const char* evalCodeCStr
 );
 
+extern uint64_t evalRubyHelperString(
+  const char* evalNameCStr,
+  const char* evalCodeCStr
+);
+
+extern uint64_t evalRubyExpressionString(
+  const char* evalNameCStr,
+  const char* evalCodeCStr
+);
+
 #endif
@@ -104,8 +104,7 @@ func TestEvalRubyString(t *testing.T) {
   assert.NotNil(t, dataObj, "Should return a non empty dataOjb")
   //spew.Dump(dataObj)
   assert.NotNil(t, dataObj.Data["evalue"], "Should return an error")
-  assert.Equal(t, dataObj.Data["evalue"], 
-    "raised TestEvalRubyString3\nin line: (0)\nraise('raised TestEvalRubyString3')",
+  assert.Equal(t, dataObj.Data["evalue"], "raised TestEvalRubyString3",
     "Should return correct error report",
   );
   assert.NotNil(t, dataObj.Data["status"], "Should be an error obj")
@@ -113,13 +112,15 @@ func TestEvalRubyString(t *testing.T) {
     "Should return correct error report",
   )
   assert.NotNil(t, dataObj.Data["ename"], "Should be an error obj")
-  assert.Equal(t, dataObj.Data["ename"], "ERROR",
-    "Should return correct error report",
+  assert.Equal(t, dataObj.Data["ename"], "RuntimeError",
+    "Should return the exception's class name",
   )
   assert.NotNil(t, dataObj.Data["traceback"], "Should be an error obj")
-  assert.Equal(t, dataObj.Data["traceback"].([]string)[0],
-    "IPyRuby kernel evaluation of Ruby code FAILED",
-    "Should return correct error report",
+  assert.Len(t, dataObj.Data["traceback"], 1,
+    "Should only return the user's backtrace frames",
+  )
+  assert.Contains(t, dataObj.Data["traceback"].([]string)[0], "(cell):1:in ",
+    "Should return the exception's backtrace",
   )
 
   dataObj = rubyState.GoEvalRubyString(
@@ -130,7 +131,7 @@ func TestEvalRubyString(t *testing.T) {
   //spew.Dump(dataObj)
   assert.NotNil(t, dataObj.Data["evalue"], "Should return an error")
   assert.Equal(t, dataObj.Data["evalue"], 
-    "<main>: syntax error, unexpected tIDENTIFIER, expecting '('\nthis code should not compile\n                     ^~~~~~~",
+    "(cell):1: syntax error, unexpected tIDENTIFIER, expecting '('\nthis code should not compile\n                     ^~~~~~~",
     "Should return correct error report",
   );
   assert.NotNil(t, dataObj.Data["status"], "Should be an error obj")
@@ -138,13 +139,11 @@ func TestEvalRubyString(t *testing.T) {
     "Should return correct error report",
   )
   assert.NotNil(t, dataObj.Data["ename"], "Should be an error obj")
-  assert.Equal(t, dataObj.Data["ename"], "ERROR",
-    "Should return correct error report",
+  assert.Equal(t, dataObj.Data["ename"], "SyntaxError",
+    "Should return the exception's class name",
   )
-  assert.NotNil(t, dataObj.Data["traceback"], "Should be an error obj")
-  assert.Equal(t, dataObj.Data["traceback"].([]string)[0],
-    "IPyRuby kernel evaluation of Ruby code FAILED",
-    "Should return correct error report",
+  assert.Nil(t, dataObj.Data["traceback"],
+    "Should not return the kernel's own backtrace frames",
   )
 }
 
@@ -2,22 +2,31 @@ package main
 
 import (
 	"flag"
-	"log"
+	"runtime"
   
   "github.com/stephengaito/goIPythonKernelToolkit/goIPyKernel"
   "github.com/stephengaito/goIPythonKernelToolkit/kernels/goIPyRuby/goIPyRubyAdaptor"
 )
 
+// Ruby (and hence its "main" Thread, used to deliver interrupts) is bound 
+// to the OS thread which starts it, so keep the main goroutine, which 
+// both starts Ruby and evaluates all code, on the main OS thread. 
+//
+func init() {
+  runtime.LockOSThread()
+}
+
 func main() {
 
-	// Parse the connection file.
+	// Parse the (optional) handshake port and the (optional) connection 
+	// file; without a connection file the kernel runs standalone.
+	handshakePort := flag.Int("handshake-port", 0,
+		"report the kernel's (random) ports to the provisioner on this port")
 	flag.Parse()
-	if flag.NArg() < 1 {
-		log.Fatalln("Need a command line argument specifying the connection file.")
-	}
 
   adaptor := goIPyRubyAdaptor.NewGoAdaptor()
   kernel  := goIPyKernel.NewIPyKernel(adaptor)
+  kernel.HandshakePort = *handshakePort
   
 	// Run the kernel.
 	kernel.Run(flag.Arg(0))